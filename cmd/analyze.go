@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Shopify/themekit/src/analyze"
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/file"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Find missing and unused theme files",
+	Long: `Analyze will scan your local liquid and JSON files for render, include,
+ section and asset_url references as well as section types in JSON templates.
+ It will then report references to files that do not exist and assets, snippets
+ and sections that are never referenced. Ignored files are handled the same
+ way as they are in deploy.
+
+ Use the --dot flag to print the dependency graph in the graphviz dot format.
+ `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmdutil.ForLocal(flags, args, analyzeTheme)
+	},
+}
+
+func analyzeTheme(ctx *cmdutil.Ctx) error {
+	filter, err := file.NewFilter(ctx.Env.Directory, ctx.Env.IgnoredFiles, ctx.Env.Ignores)
+	if err != nil {
+		return err
+	}
+
+	assets, err := shopify.FindAssets(ctx.Env)
+	if err != nil {
+		return err
+	}
+
+	graph := analyze.New(assets)
+
+	if ctx.Flags.Dot {
+		var buf bytes.Buffer
+		if err := graph.WriteDot(&buf); err != nil {
+			return err
+		}
+		if ctx.Flags.Output == "" {
+			ctx.Log.Print(buf.String())
+			return nil
+		}
+		return ioutil.WriteFile(ctx.Flags.Output, buf.Bytes(), 0644)
+	}
+
+	missing := graph.Missing(filter.Match)
+	for _, ref := range missing {
+		ctx.Err("[%s] %s references %s which does not exist", colors.Green(ctx.Env.Name), colors.Blue(ref.From), colors.Yellow(ref.To))
+	}
+
+	unused := graph.Unused()
+	for _, key := range unused {
+		ctx.Log.Printf("[%s] %s %s", colors.Green(ctx.Env.Name), colors.Yellow("Unused"), colors.Blue(key))
+	}
+
+	ctx.Log.Printf(
+		"[%s] %d files, %s: %d, %s: %d",
+		colors.Green(ctx.Env.Name),
+		len(assets),
+		colors.Red("Missing"), len(missing),
+		colors.Yellow("Unused"), len(unused),
+	)
+	return nil
+}
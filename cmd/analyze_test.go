@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyzeTheme(t *testing.T) {
+	ctx, _, _, stdOut, stdErr := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "analyzedir")
+	ctx.Env.IgnoredFiles = []string{"snippets/ignored.liquid"}
+	assert.Nil(t, analyzeTheme(ctx))
+	assert.Contains(t, stdErr.String(), "layout/theme.liquid references snippets/gone.liquid which does not exist")
+	assert.NotContains(t, stdErr.String(), "snippets/ignored.liquid")
+	assert.Contains(t, stdOut.String(), "Unused snippets/dead.liquid")
+	assert.NotContains(t, stdOut.String(), "Unused snippets/used.liquid")
+	assert.Contains(t, stdOut.String(), "3 files, Missing: 1, Unused: 1")
+
+	ctx, _, _, stdOut, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "analyzedir")
+	ctx.Flags.Dot = true
+	assert.Nil(t, analyzeTheme(ctx))
+	assert.Contains(t, stdOut.String(), `"layout/theme.liquid" -> "snippets/used.liquid";`)
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "analyzedir")
+	ctx.Flags.Dot = true
+	ctx.Flags.Output = filepath.Join("_testdata", "graph.dot")
+	defer os.Remove(ctx.Flags.Output)
+	assert.Nil(t, analyzeTheme(ctx))
+	data, _ := ioutil.ReadFile(ctx.Flags.Output)
+	assert.Contains(t, string(data), "digraph theme {")
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Env.Directory = "nope"
+	assert.NotNil(t, analyzeTheme(ctx))
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Env.Ignores = []string{"nope"}
+	assert.NotNil(t, analyzeTheme(ctx))
+}
@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Shopify/themekit/src/archive"
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/file"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+const manifestName = "manifest.json"
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Save a copy of the remote theme as a zip or tar.gz archive",
+	Long: `Backup will download every file of the theme on shopify into a timestamped
+ archive named after the store and theme. The archive contains a manifest.json
+ with the shop, theme and a checksum of every file so that it can be verified
+ when it is restored. Files are written to the archive as they are downloaded.
+
+ Use --output to choose the directory or archive file to write to and --format
+ to choose between zip and tar.gz. Ignored files are not backed up, use the
+ --no-ignore flag to make a complete backup.
+
+ Backups can be pushed back to shopify with 'theme restore'.
+ `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// backup only reads from the theme so it is safe to run on the live theme
+		flags.AllowLive = true
+		return cmdutil.ForEachClient(flags, args, backup)
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <archive>",
+	Short: "Upload a theme archive created by backup",
+	Long: `Restore will upload every file in an archive created by 'theme backup' to
+ the theme in your config. Pass the --name flag to create a new theme with
+ that name and restore into it instead. The checksums in the archive manifest
+ are verified before anything is uploaded. Files on shopify that are not in the
+ archive are not removed.
+
+ Pass the --dry-run flag to verify the archive and list the files that would be
+ uploaded without changing anything.
+ `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flags.Name != "" && flags.ThemeID == "" {
+			// This is a hack to get around theme ID validation because a new theme is created
+			flags.ThemeID = "1337"
+		}
+		return cmdutil.ForSingleClient(flags, args, restore)
+	},
+}
+
+type backupManifest struct {
+	Shop      string          `json:"shop"`
+	Domain    string          `json:"domain"`
+	ThemeID   int64           `json:"theme_id"`
+	ThemeName string          `json:"theme_name"`
+	Role      string          `json:"role"`
+	CreatedAt time.Time       `json:"created_at"`
+	Assets    []manifestAsset `json:"assets"`
+}
+
+type manifestAsset struct {
+	Key      string `json:"key"`
+	Checksum string `json:"checksum"`
+}
+
+func backup(ctx *cmdutil.Ctx) error {
+	if ctx.Flags.Format != archive.Zip && ctx.Flags.Format != archive.TarGz {
+		return fmt.Errorf("[%s] unknown backup format %s, use %s or %s", colors.Green(ctx.Env.Name), ctx.Flags.Format, archive.Zip, archive.TarGz)
+	}
+
+	theme, err := ctx.Client.GetInfo()
+	if err != nil {
+		return err
+	}
+
+	assets, err := ctx.Client.GetAllAssets()
+	if err != nil {
+		return err
+	} else if len(assets) == 0 {
+		return fmt.Errorf("[%s] no files to backup", colors.Green(ctx.Env.Name))
+	}
+
+	manifest := backupManifest{
+		Shop:      ctx.Shop.Name,
+		Domain:    ctx.Env.Domain,
+		ThemeID:   theme.ID,
+		ThemeName: theme.Name,
+		Role:      theme.Role,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	path := backupPath(ctx, manifest)
+	format := archive.Format(path)
+	if format == "" {
+		format = ctx.Flags.Format
+	}
+
+	// the archive is written to a temporary file so that a failed backup never
+	// leaves behind an archive that looks complete
+	partPath := path + ".part"
+	out, err := os.Create(partPath)
+	if err != nil {
+		return err
+	}
+	writer, err := archive.NewWriter(out, format)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	var mu sync.Mutex
+	var backupGroup sync.WaitGroup
+	failed := 0
+	ctx.StartProgress(len(assets))
+	for _, asset := range assets {
+		backupGroup.Add(1)
+		go func(key string) {
+			defer backupGroup.Done()
+			defer ctx.DoneTask(file.Get)
+			assetLimitSemaphore <- struct{}{}
+			defer func() { <-assetLimitSemaphore }()
+
+			data, err := downloadData(ctx, key)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				err = writer.Add(key, data)
+			}
+			if err != nil {
+				failed++
+				ctx.Err("[%s] error backing up %s: %s", colors.Green(ctx.Env.Name), colors.Blue(key), err)
+				return
+			}
+			manifest.Assets = append(manifest.Assets, manifestAsset{Key: key, Checksum: shopify.NewAsset(key, data).Checksum})
+		}(asset.Key)
+	}
+	backupGroup.Wait()
+
+	if failed == 0 {
+		err = writeManifest(writer, manifest)
+	}
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && failed > 0 {
+		err = fmt.Errorf("[%s] backup incomplete, %d files could not be downloaded", colors.Green(ctx.Env.Name), failed)
+	}
+	if err != nil {
+		os.Remove(partPath)
+		return err
+	}
+
+	if err := os.Rename(partPath, path); err != nil {
+		return err
+	}
+	ctx.Log.Printf("[%s] backup of theme %d written to %s", colors.Green(ctx.Env.Name), theme.ID, colors.Blue(path))
+	return nil
+}
+
+// downloadData will fetch the raw contents of an asset. Unlike asset.Contents the
+// value is not reindented so that the backup is identical to what is on shopify.
+func downloadData(ctx *cmdutil.Ctx, key string) ([]byte, error) {
+	asset, err := ctx.Client.GetAsset(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(asset.Attachment) > 0 {
+		data, err := base64.StdEncoding.DecodeString(asset.Attachment)
+		if err != nil {
+			return nil, fmt.Errorf("Could not decode %s. error: %s", key, err)
+		}
+		return data, nil
+	}
+	return []byte(asset.Value), nil
+}
+
+func writeManifest(writer archive.Writer, manifest backupManifest) error {
+	sort.Slice(manifest.Assets, func(i, j int) bool { return manifest.Assets[i].Key < manifest.Assets[j].Key })
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writer.Add(manifestName, data)
+}
+
+// backupPath will return the archive path for a backup. If the output flag is an
+// archive it is used as is, otherwise it is used as the directory for a file
+// named after the store, theme and time of the backup.
+func backupPath(ctx *cmdutil.Ctx, manifest backupManifest) string {
+	if archive.Format(ctx.Flags.Output) != "" {
+		return ctx.Flags.Output
+	}
+	store := strings.TrimSuffix(ctx.Env.Domain, ".myshopify.com")
+	name := fmt.Sprintf("%s-%d-%s.%s", store, manifest.ThemeID, manifest.CreatedAt.Format("20060102T150405Z"), ctx.Flags.Format)
+	return filepath.Join(ctx.Flags.Output, name)
+}
+
+func restore(ctx *cmdutil.Ctx) error {
+	if len(ctx.Args) != 1 {
+		return fmt.Errorf("please specify the archive to restore")
+	} else if ctx.Env.ReadOnly && ctx.Flags.Name == "" {
+		return fmt.Errorf("[%s] environment is readonly", colors.Green(ctx.Env.Name))
+	}
+	path := ctx.Args[0]
+
+	keys, err := verifyArchive(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Flags.DryRun {
+		ctx.DisableSummary()
+		if ctx.Flags.Name != "" {
+			ctx.Log.Printf("[%s] %s theme %s", colors.Green(ctx.Env.Name), colors.Green("Create"), colors.Yellow(ctx.Flags.Name))
+		}
+		for _, key := range keys {
+			ctx.Log.Printf("[%s] %s %s", colors.Green(ctx.Env.Name), colors.Green("Update"), colors.Blue(key))
+		}
+		ctx.Log.Printf("[%s] dry run, %s: %d", colors.Green(ctx.Env.Name), colors.Green("Update"), len(keys))
+		return nil
+	}
+
+	if ctx.Flags.Name != "" {
+		theme, err := ctx.Client.CreateNewTheme(ctx.Flags.Name)
+		if err != nil {
+			return err
+		}
+		ctx.Env.ThemeID = fmt.Sprintf("%v", theme.ID)
+		ctx.Log.Printf("[%s] theme %s created with id %v", colors.Green(ctx.Env.Name), colors.Yellow(theme.Name), colors.Yellow(theme.ID))
+	}
+
+	var restoreGroup sync.WaitGroup
+	var settingsData *shopify.Asset
+	restorable := map[string]bool{}
+	for _, key := range keys {
+		restorable[key] = true
+	}
+	ctx.StartProgress(len(keys))
+	err = archive.Walk(path, func(name string, data []byte) error {
+		if !restorable[name] {
+			return nil
+		}
+		asset := shopify.NewAssetForEnv(ctx.Env, name, data)
+		if name == settingsDataKey {
+			settingsData = &asset
+			return nil
+		}
+		restoreGroup.Add(1)
+		assetLimitSemaphore <- struct{}{}
+		go func() {
+			defer restoreGroup.Done()
+			defer func() { <-assetLimitSemaphore }()
+			defer ctx.DoneTask(file.Update)
+			upload(ctx, asset, "")
+		}()
+		return nil
+	})
+	restoreGroup.Wait()
+	if err != nil {
+		return err
+	}
+
+	// settings data is uploaded last because it references the other files
+	if settingsData != nil {
+		upload(ctx, *settingsData, "")
+		ctx.DoneTask(file.Update)
+	}
+	return nil
+}
+
+// verifyArchive will check every file in an archive against the checksums in its
+// manifest and return the keys of the theme files that will be restored.
+func verifyArchive(ctx *cmdutil.Ctx, path string) ([]string, error) {
+	var manifest *backupManifest
+	checksums := map[string]string{}
+	err := archive.Walk(path, func(name string, data []byte) error {
+		if name == manifestName {
+			manifest = &backupManifest{}
+			return json.Unmarshal(data, manifest)
+		}
+		checksums[name] = shopify.NewAsset(name, data).Checksum
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %s", path, err)
+	} else if manifest == nil {
+		return nil, fmt.Errorf("%s has no %s, only archives created by backup can be restored", path, manifestName)
+	}
+
+	keys := []string{}
+	for _, asset := range manifest.Assets {
+		if checksum, ok := checksums[asset.Key]; !ok {
+			ctx.Err("[%s] %s is missing from the archive", colors.Green(ctx.Env.Name), colors.Blue(asset.Key))
+		} else if checksum != asset.Checksum {
+			ctx.Err("[%s] %s does not match the checksum in the manifest", colors.Green(ctx.Env.Name), colors.Blue(asset.Key))
+		} else {
+			keys = append(keys, asset.Key)
+		}
+	}
+	if len(keys) != len(manifest.Assets) {
+		return nil, fmt.Errorf("[%s] %s is corrupt, nothing was restored", colors.Green(ctx.Env.Name), path)
+	}
+
+	ctx.Log.Printf(
+		"[%s] restoring %d files from theme %s (%d) on %s, backed up %s",
+		colors.Green(ctx.Env.Name),
+		len(keys),
+		colors.Yellow(manifest.ThemeName),
+		manifest.ThemeID,
+		colors.Yellow(manifest.Domain),
+		manifest.CreatedAt.Format(time.RFC3339),
+	)
+	return keys, nil
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Shopify/themekit/src/archive"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "backup")
+	defer os.RemoveAll(dir)
+
+	remote := []shopify.Asset{
+		{Key: "config/settings_data.json", Value: `{"current":"Default"}`},
+		{Key: "layout/theme.liquid", Value: "{{ content_for_layout }}"},
+		{Key: "assets/logo.png", Attachment: "iVBORw0KGgo="},
+	}
+
+	for _, format := range []string{archive.Zip, archive.TarGz} {
+		ctx, client, _, stdOut, _ := createTestCtx()
+		ctx.Env.Domain = "store.myshopify.com"
+		ctx.Flags.Format = format
+		ctx.Flags.Output = dir
+		client.On("GetInfo").Return(shopify.Theme{ID: 42, Name: "Dawn", Role: "main"}, nil)
+		client.On("GetAllAssets").Return(remote, nil)
+		for _, asset := range remote {
+			client.On("GetAsset", asset.Key).Return(asset, nil)
+		}
+		assert.Nil(t, backup(ctx))
+		assert.Contains(t, stdOut.String(), "backup of theme 42 written to")
+
+		paths, _ := filepath.Glob(filepath.Join(dir, "store-42-*."+format))
+		if !assert.Equal(t, 1, len(paths), format) {
+			continue
+		}
+		assert.Equal(t, format, archive.Format(paths[0]))
+		archived := map[string]string{}
+		archive.Walk(paths[0], func(name string, data []byte) error {
+			archived[name] = string(data)
+			return nil
+		})
+		assert.Equal(t, `{"current":"Default"}`, archived["config/settings_data.json"])
+
+		ctx, client, _, stdOut, _ = createTestCtx()
+		ctx.Args = []string{paths[0]}
+		ctx.Flags.DryRun = true
+		assert.Nil(t, restore(ctx))
+		assert.Contains(t, stdOut.String(), "restoring 3 files from theme Dawn (42) on store.myshopify.com")
+		assert.Contains(t, stdOut.String(), "Update layout/theme.liquid")
+		assert.Contains(t, stdOut.String(), "dry run, Update: 3")
+		client.AssertNotCalled(t, "UpdateAsset", mock.Anything, mock.Anything)
+
+		ctx, client, _, _, _ = createTestCtx()
+		ctx.Args = []string{paths[0]}
+		ctx.Flags.Name = "Restored"
+		var mu sync.Mutex
+		uploaded := []string{}
+		client.On("CreateNewTheme", "Restored").Return(shopify.Theme{ID: 43, Name: "Restored"}, nil)
+		client.On("UpdateAsset", mock.Anything, "").Run(func(args mock.Arguments) {
+			mu.Lock()
+			defer mu.Unlock()
+			uploaded = append(uploaded, args.Get(0).(shopify.Asset).Key)
+		}).Return(nil)
+		assert.Nil(t, restore(ctx))
+		assert.Equal(t, "43", ctx.Env.ThemeID)
+		if assert.Equal(t, 3, len(uploaded)) {
+			assert.Equal(t, "config/settings_data.json", uploaded[2])
+		}
+		client.AssertCalled(t, "UpdateAsset", shopify.Asset{Key: "layout/theme.liquid", Value: "{{ content_for_layout }}", Checksum: "85f7a7ef83fb0dfa183402fd18d98a4f"}, "")
+	}
+
+	ctx, client, _, _, stdErr := createTestCtx()
+	ctx.Flags.Format = archive.Zip
+	ctx.Flags.Output = filepath.Join(dir, "failed.zip")
+	client.On("GetInfo").Return(shopify.Theme{ID: 42}, nil)
+	client.On("GetAllAssets").Return(remote, nil)
+	client.On("GetAsset", mock.Anything).Return(shopify.Asset{}, fmt.Errorf("server error"))
+	err := backup(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "backup incomplete, 3 files could not be downloaded")
+	}
+	assert.Contains(t, stdErr.String(), "error backing up layout/theme.liquid: server error")
+	_, err = os.Stat(ctx.Flags.Output)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(ctx.Flags.Output + ".part")
+	assert.True(t, os.IsNotExist(err))
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Flags.Format = "rar"
+	assert.NotNil(t, backup(ctx))
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Flags.Format = archive.Zip
+	client.On("GetInfo").Return(shopify.Theme{}, fmt.Errorf("not found"))
+	assert.NotNil(t, backup(ctx))
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Flags.Format = archive.Zip
+	client.On("GetInfo").Return(shopify.Theme{}, nil)
+	client.On("GetAllAssets").Return([]shopify.Asset{}, nil)
+	err = backup(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "no files to backup")
+	}
+}
+
+func TestRestoreVerification(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "restore")
+	defer os.RemoveAll(dir)
+
+	writeArchive := func(name string, files map[string]string) string {
+		path := filepath.Join(dir, name)
+		writer, _ := archive.Create(path)
+		for key, value := range files {
+			writer.Add(key, []byte(value))
+		}
+		writer.Close()
+		return path
+	}
+
+	manifest := `{"theme_id": 42, "assets": [{"key": "layout/theme.liquid", "checksum": "85f7a7ef83fb0dfa183402fd18d98a4f"}, {"key": "snippets/gone.liquid", "checksum": "nope"}]}`
+
+	testcases := []struct {
+		files    map[string]string
+		readonly bool
+		err      string
+		stdErr   string
+	}{
+		{files: map[string]string{"layout/theme.liquid": "{{ content_for_layout }}"}, err: "has no manifest.json"},
+		{files: map[string]string{"manifest.json": "{", "layout/theme.liquid": "tampered"}, err: "could not read"},
+		{files: map[string]string{"manifest.json": manifest, "layout/theme.liquid": "tampered"}, err: "is corrupt, nothing was restored", stdErr: "layout/theme.liquid does not match the checksum"},
+		{files: map[string]string{"manifest.json": manifest, "layout/theme.liquid": "{{ content_for_layout }}"}, err: "is corrupt", stdErr: "snippets/gone.liquid is missing from the archive"},
+		{files: map[string]string{"manifest.json": manifest}, readonly: true, err: "environment is readonly"},
+	}
+
+	for i, testcase := range testcases {
+		ctx, client, _, _, stdErr := createTestCtx()
+		ctx.Args = []string{writeArchive(fmt.Sprintf("%d.zip", i), testcase.files)}
+		ctx.Env.ReadOnly = testcase.readonly
+		err := restore(ctx)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), testcase.err)
+		}
+		if testcase.stdErr != "" {
+			assert.Contains(t, stdErr.String(), testcase.stdErr)
+		}
+		client.AssertNotCalled(t, "UpdateAsset", mock.Anything, mock.Anything)
+	}
+
+	ctx, _, _, _, _ := createTestCtx()
+	err := restore(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "please specify the archive to restore")
+	}
+}
+
+func TestBackupPath(t *testing.T) {
+	ctx, _, _, _, _ := createTestCtx()
+	ctx.Env.Domain = "store.myshopify.com"
+	ctx.Flags.Format = archive.TarGz
+	manifest := backupManifest{ThemeID: 42, CreatedAt: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	assert.Equal(t, "store-42-20200102T030405Z.tar.gz", backupPath(ctx, manifest))
+
+	ctx.Flags.Output = "backups"
+	assert.Equal(t, filepath.Join("backups", "store-42-20200102T030405Z.tar.gz"), backupPath(ctx, manifest))
+
+	ctx.Flags.Output = filepath.Join("backups", "nightly.zip")
+	assert.Equal(t, filepath.Join("backups", "nightly.zip"), backupPath(ctx, manifest))
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/file"
+)
+
+// gitDiff will list the files that changed in dir since ref with their status,
+// paths are relative to dir and renames are detected. Refs that start with a dash
+// are rejected so that they cannot be read as options by git.
+var gitDiff = func(dir, ref string) ([]byte, error) {
+	if strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("invalid revision %s, it cannot start with -", ref)
+	}
+	cmd := exec.Command("git", "diff", "--name-status", "-z", "-M", "--relative", ref, "--")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not list changes since %s: %s %s", ref, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// parseGitChanges will turn the output of git diff --name-status -z into the
+// operations needed to apply it. A renamed file is removed at its old path and
+// updated at its new path.
+func parseGitChanges(out []byte) (map[string]file.Op, error) {
+	changes := map[string]file.Op{}
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	for i := 0; i < len(fields) && fields[i] != ""; i++ {
+		status := fields[i]
+		switch status[0] {
+		case 'R', 'C':
+			if i+2 >= len(fields) {
+				return changes, fmt.Errorf("unexpected git output for %s", status)
+			}
+			if status[0] == 'R' {
+				changes[filepath.ToSlash(fields[i+1])] = file.Remove
+			}
+			changes[filepath.ToSlash(fields[i+2])] = file.Update
+			i += 2
+		default:
+			if i+1 >= len(fields) {
+				return changes, fmt.Errorf("unexpected git output for %s", status)
+			}
+			if status[0] == 'D' {
+				changes[filepath.ToSlash(fields[i+1])] = file.Remove
+			} else {
+				changes[filepath.ToSlash(fields[i+1])] = file.Update
+			}
+			i++
+		}
+	}
+	return changes, nil
+}
+
+// changedSinceActions will plan a deploy of only the files that git reports as
+// changed since the ref in --changed-since. Files that were deleted or renamed
+// are removed from shopify unless --nodelete is set.
+func changedSinceActions(ctx *cmdutil.Ctx) (map[string]file.Op, error) {
+	assetsActions := map[string]file.Op{}
+	if len(ctx.Args) > 0 {
+		return assetsActions, fmt.Errorf("--changed-since cannot be used when deploying specific files")
+	}
+
+	out, err := gitDiff(ctx.Env.Directory, ctx.Flags.ChangedSince)
+	if err != nil {
+		return assetsActions, err
+	}
+	changes, err := parseGitChanges(out)
+	if err != nil {
+		return assetsActions, err
+	}
+
+	filter, err := file.NewFilter(ctx.Env.Directory, ctx.Env.IgnoredFiles, ctx.Env.Ignores)
+	if err != nil {
+		return assetsActions, err
+	}
+
+	remoteFiles, err := ctx.Client.GetAllAssets()
+	if err != nil {
+		return assetsActions, err
+	}
+	remote := map[string]bool{}
+	for _, asset := range remoteFiles {
+		remote[asset.Key] = true
+	}
+
+	for path, op := range changes {
+		if filter.Match(path) {
+			continue
+		}
+		_, statErr := os.Stat(filepath.Join(ctx.Env.Directory, filepath.FromSlash(path)))
+		if op == file.Update && statErr != nil {
+			// the file was changed in git but does not exist in the working tree
+			op = file.Remove
+		}
+		if op == file.Remove {
+			if ctx.Flags.NoDelete || !remote[path] || statErr == nil {
+				continue
+			}
+		}
+		assetsActions[path] = op
+	}
+
+	if ctx.Flags.Verbose {
+		ctx.Log.Printf("[%s] %d files changed since %s", colors.Green(ctx.Env.Name), len(assetsActions), colors.Yellow(ctx.Flags.ChangedSince))
+	}
+	return assetsActions, nil
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Shopify/themekit/src/file"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+func TestParseGitChanges(t *testing.T) {
+	changes, err := parseGitChanges([]byte("M\x00assets/app.js\x00A\x00snippets/new.liquid\x00D\x00snippets/old.liquid\x00R100\x00sections/a.liquid\x00sections/b.liquid\x00C75\x00templates/a.json\x00templates/b.json\x00"))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]file.Op{
+		"assets/app.js":       file.Update,
+		"snippets/new.liquid": file.Update,
+		"snippets/old.liquid": file.Remove,
+		"sections/a.liquid":   file.Remove,
+		"sections/b.liquid":   file.Update,
+		"templates/b.json":    file.Update,
+	}, changes)
+
+	changes, err = parseGitChanges([]byte(""))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]file.Op{}, changes)
+
+	_, err = parseGitChanges([]byte("R100\x00sections/a.liquid\x00"))
+	assert.NotNil(t, err)
+	_, err = parseGitChanges([]byte("M\x00"))
+	assert.NotNil(t, err)
+}
+
+func TestChangedSinceActions(t *testing.T) {
+	defer func(diff func(string, string) ([]byte, error)) { gitDiff = diff }(gitDiff)
+	gitDiff = func(dir, ref string) ([]byte, error) {
+		assert.Equal(t, "HEAD~1", ref)
+		return []byte("M\x00assets/app.js\x00D\x00snippets/old.liquid\x00D\x00snippets/never-uploaded.liquid\x00M\x00config.yml\x00D\x00assets/theme.css\x00M\x00snippets/gone.liquid\x00"), nil
+	}
+
+	ctx, client, _, _, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	ctx.Flags.ChangedSince = "HEAD~1"
+	client.On("GetAllAssets").Return([]shopify.Asset{
+		{Key: "assets/app.js"},
+		{Key: "assets/theme.css.liquid", Compiled: "assets/theme.css"},
+		{Key: "snippets/old.liquid"},
+		{Key: "snippets/gone.liquid"},
+	}, nil)
+	actions, err := changedSinceActions(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]file.Op{
+		"assets/app.js":        file.Update,
+		"snippets/old.liquid":  file.Remove,
+		"snippets/gone.liquid": file.Remove,
+	}, actions)
+
+	ctx.Flags.NoDelete = true
+	actions, err = changedSinceActions(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]file.Op{"assets/app.js": file.Update}, actions)
+
+	ctx.Args = []string{"assets/app.js"}
+	_, err = changedSinceActions(ctx)
+	assert.EqualError(t, err, "--changed-since cannot be used when deploying specific files")
+
+	gitDiff = func(dir, ref string) ([]byte, error) { return nil, fmt.Errorf("bad revision") }
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Flags.ChangedSince = "nope"
+	_, err = changedSinceActions(ctx)
+	assert.EqualError(t, err, "bad revision")
+}
+
+func TestGitDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, _ := ioutil.TempDir("", "changed")
+	defer os.RemoveAll(dir)
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		assert.Nil(t, err, string(out))
+	}
+	theme := filepath.Join(dir, "theme")
+	os.MkdirAll(filepath.Join(theme, "snippets"), 0755)
+	ioutil.WriteFile(filepath.Join(theme, "snippets", "a.liquid"), []byte("a snippet that is long enough to be detected as a rename"), 0644)
+	ioutil.WriteFile(filepath.Join(theme, "snippets", "b.liquid"), []byte("b"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("readme"), 0644)
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+
+	os.Rename(filepath.Join(theme, "snippets", "a.liquid"), filepath.Join(theme, "snippets", "renamed.liquid"))
+	os.Remove(filepath.Join(theme, "snippets", "b.liquid"))
+	ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("changed"), 0644)
+	git("add", "-A")
+	git("commit", "-q", "-m", "changes")
+
+	out, err := gitDiff(theme, "HEAD~1")
+	assert.Nil(t, err)
+	changes, err := parseGitChanges(out)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]file.Op{
+		"snippets/a.liquid":       file.Remove,
+		"snippets/renamed.liquid": file.Update,
+		"snippets/b.liquid":       file.Remove,
+	}, changes)
+
+	_, err = gitDiff(theme, "not-a-ref")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not list changes since not-a-ref")
+	}
+
+	output := filepath.Join(dir, "output")
+	_, err = gitDiff(theme, "--output="+output)
+	assert.EqualError(t, err, "invalid revision --output="+output+", it cannot start with -")
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDeployChangedSince(t *testing.T) {
+	defer func(diff func(string, string) ([]byte, error)) { gitDiff = diff }(gitDiff)
+	gitDiff = func(dir, ref string) ([]byte, error) {
+		return []byte("M\x00assets/app.js\x00D\x00snippets/old.liquid\x00"), nil
+	}
+
+	ctx, client, _, _, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	ctx.Flags.ChangedSince = "main"
+	client.On("GetAllAssets").Return([]shopify.Asset{{Key: "snippets/old.liquid"}, {Key: "config/settings_data.json"}}, nil)
+	client.On("UpdateAsset", mock.MatchedBy(func(asset shopify.Asset) bool { return asset.Key == "assets/app.js" }), "").Return(nil)
+	client.On("DeleteAsset", shopify.Asset{Key: "snippets/old.liquid"}).Return(nil)
+	assert.Nil(t, deploy(ctx))
+	client.AssertExpectations(t)
+	client.AssertNotCalled(t, "DeleteAsset", shopify.Asset{Key: "config/settings_data.json"})
+}
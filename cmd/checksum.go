@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+var checksumCmd = &cobra.Command{
+	Use:   "checksum <filenames>",
+	Short: "Compare the checksums of files with the checksums on shopify",
+	Long: `Checksum will show the local and remote checksum of each file and how the
+ local checksum was calculated, to help find out why deploy, download or status
+ see a file as changed.
+
+ Local checksums are calculated the same way shopify calculates them:
+
+   binary files    the md5 of the contents
+   json files      the md5 of the compacted json, json that cannot be parsed,
+                   like settings_data.json with a comment header, is used as is
+   text files      the md5 of the contents, with CRLF replaced by LF when
+                   normalize_line_endings is set
+
+ Set text_extensions or binary_extensions in the config to change whether files
+ are treated as text or binary. Assets that shopify compiles from a .js.liquid,
+ .css.liquid or .scss.liquid file, like assets/app.js from assets/app.js.liquid,
+ are never compared, deployed or downloaded.
+ `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// checksum only reads from the theme so it is safe to run on the live theme
+		flags.AllowLive = true
+		return cmdutil.ForEachClient(flags, args, checksum)
+	},
+}
+
+func checksum(ctx *cmdutil.Ctx) error {
+	ctx.DisableSummary()
+
+	if len(ctx.Args) == 0 {
+		return fmt.Errorf("please specify the files to check")
+	}
+
+	remoteFiles, err := ctx.Client.GetAllAssets()
+	if err != nil {
+		return err
+	}
+	remote := map[string]string{}
+	compiled := map[string]string{}
+	for _, asset := range remoteFiles {
+		remote[asset.Key] = asset.Checksum
+		if asset.Compiled != "" {
+			compiled[asset.Compiled] = asset.Key
+		}
+	}
+
+	report := []string{fmt.Sprintf("[%s] theme %s", colors.Green(ctx.Env.Name), colors.Yellow(ctx.Env.ThemeID))}
+	for _, key := range checksumKeys(ctx) {
+		report = append(report, colors.Blue(key))
+
+		data, err := ioutil.ReadFile(filepath.Join(ctx.Env.Directory, filepath.FromSlash(key)))
+		localChecksum := ""
+		if err != nil {
+			report = append(report, fmt.Sprintf("\tlocal:   %s", colors.Yellow("missing")))
+		} else {
+			localChecksum = shopify.NewAssetForEnv(ctx.Env, key, data).Checksum
+			report = append(report, fmt.Sprintf("\tlocal:   %s (%s)", localChecksum, shopify.ChecksumStrategy(ctx.Env, key, data)))
+		}
+
+		source, isCompiled := compiled[key]
+		remoteChecksum, ok := remote[key]
+		if isCompiled {
+			report = append(report, fmt.Sprintf("\tshopify: compiled from %s", colors.Blue(source)))
+		} else if !ok {
+			report = append(report, fmt.Sprintf("\tshopify: %s", colors.Yellow("missing")))
+		} else {
+			report = append(report, fmt.Sprintf("\tshopify: %s", remoteChecksum))
+		}
+
+		if isCompiled {
+			report = append(report, fmt.Sprintf("\t%s", colors.Cyan("not compared")))
+		} else if localChecksum != "" && localChecksum == remoteChecksum {
+			report = append(report, fmt.Sprintf("\t%s", colors.Green("match")))
+		} else {
+			report = append(report, fmt.Sprintf("\t%s", colors.Red("mismatch")))
+		}
+	}
+	ctx.Log.Print(strings.Join(report, "\n"))
+	return nil
+}
+
+// checksumKeys will return the asset keys of the files passed in. Directories are
+// expanded to the files in them.
+func checksumKeys(ctx *cmdutil.Ctx) []string {
+	keys := []string{}
+	for _, path := range ctx.Args {
+		if info, err := os.Stat(filepath.Join(ctx.Env.Directory, path)); err == nil && info.IsDir() {
+			if assets, err := shopify.FindChecksums(ctx.Env, nil, path); err == nil {
+				for _, asset := range assets {
+					keys = append(keys, asset.Key)
+				}
+			}
+			continue
+		}
+		keys = append(keys, filepath.ToSlash(filepath.Clean(path)))
+	}
+	return keys
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+func TestChecksum(t *testing.T) {
+	empty := "d41d8cd98f00b204e9800998ecf8427e"
+
+	ctx, client, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	ctx.Args = []string{"assets", "config/settings_data.json", "assets/theme.css", "snippets/nope.liquid"}
+	client.On("GetAllAssets").Return([]shopify.Asset{
+		{Key: "assets/app.js", Checksum: empty},
+		{Key: "assets/theme.css.liquid", Checksum: "source", Compiled: "assets/theme.css"},
+		{Key: "config/settings_data.json", Checksum: "changed"},
+	}, nil)
+	assert.Nil(t, checksum(ctx))
+	out := stdOut.String()
+	assert.Contains(t, out, "assets/app.js\n\tlocal:   "+empty+" (text)\n\tshopify: "+empty+"\n\tmatch")
+	assert.Contains(t, out, "config/settings_data.json\n\tlocal:   "+empty+" (json that could not be parsed, as it is)\n\tshopify: changed\n\tmismatch")
+	assert.Contains(t, out, "assets/theme.css\n\tlocal:   missing\n\tshopify: compiled from assets/theme.css.liquid\n\tnot compared")
+	assert.Contains(t, out, "snippets/nope.liquid\n\tlocal:   missing\n\tshopify: missing\n\tmismatch")
+
+	ctx, _, _, _, _ = createTestCtx()
+	assert.EqualError(t, checksum(ctx), "please specify the files to check")
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Args = []string{"assets/app.js"}
+	client.On("GetAllAssets").Return([]shopify.Asset{}, fmt.Errorf("server error"))
+	assert.EqualError(t, checksum(ctx), "server error")
+}
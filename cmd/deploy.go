@@ -4,7 +4,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"text/template"
 
@@ -43,6 +47,47 @@ var deployCmd = &cobra.Command{
  exist on your local machine will be removed from shopify unless the --nodelete
  flag is passed
 
+ Pass the --merge flag to merge config/settings_data.json and JSON templates with
+ the versions on shopify. Each value is compared with the version that was last
+ deployed or downloaded, kept in .themekit/merge-base, so that local changes and
+ changes made in the theme editor are both kept. If a value was changed in both
+ places, or there is no last version yet, the value on shopify is kept and only
+ new local settings are added.
+ Pass the --dry-run flag to see what would change, including the merged files,
+ without changing anything.
+
+ Pass --changed-since <ref> to only deploy the files that git reports as added,
+ modified, renamed or deleted since that revision, for example the commit before
+ a merged pull request. Deleted and renamed files are removed from shopify unless
+ --nodelete is passed. No other files are read or compared.
+
+ Every deploy of the whole theme writes a journal of its planned and completed
+ operations to .themekit/deploy-<env>.journal, which is removed once the deploy
+ succeeds. If a deploy is interrupted or some files fail, pass --resume to
+ continue it. Files that were already deployed are skipped and the ones that
+ failed are retried, nothing is compared with shopify again. Deploying only some
+ files, by name, with --changed-since or with --retry-failed, does not change the
+ journal.
+
+ The files that fail to deploy are saved in .themekit/failed-deploy-<env>.json.
+ Pass --retry-failed to deploy only those files again, for example after fixing
+ the liquid errors that shopify reported. Deploying only some files, by name or
+ with --changed-since, keeps the saved failures of the other files.
+
+ The checksums of local files are cached in .themekit/checksums.json so that
+ only files that changed since the last deploy are read. Files are only loaded
+ into memory when they are uploaded.
+
+ Files are uploaded as text or binary depending on their contents. Set
+ text_extensions or binary_extensions in the config to force files with those
+ extensions, like svg or woff2, to be uploaded as text or binary. Set
+ normalize_line_endings to convert CRLF to LF in text files before they are
+ compared and uploaded, so that CRLF checkouts are not seen as changed.
+
+ Assets that shopify compiles from a liquid file that is also in the project,
+ like assets/app.js next to assets/app.js.liquid, are not deployed because
+ uploading them would replace their liquid source on shopify.
+
  For more information, refer to https://shopify.dev/tools/theme-kit/command-reference#deploy.
  `,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -57,30 +102,220 @@ func deploy(ctx *cmdutil.Ctx) error {
 		return fmt.Errorf("[%s] environment is readonly", colors.Green(ctx.Env.Name))
 	}
 
-	assetsActions, err := generateActions(ctx)
+	if ctx.Flags.RetryFailed && (ctx.Flags.Resume || ctx.Flags.ChangedSince != "") {
+		return fmt.Errorf("--retry-failed cannot be used with --resume or --changed-since")
+	} else if ctx.Flags.Resume {
+		return resumeDeploy(ctx)
+	}
+
+	var assetsActions map[string]file.Op
+	var err error
+	if ctx.Flags.RetryFailed {
+		assetsActions, err = loadFailures(ctx, "deploy")
+	} else if ctx.Flags.ChangedSince != "" {
+		assetsActions, err = changedSinceActions(ctx)
+	} else {
+		assetsActions, err = generateActions(ctx)
+	}
 	if err != nil {
 		return err
 	}
+	skipCompiledOutputs(ctx, assetsActions)
 
-	var deployGroup sync.WaitGroup
+	if ctx.Flags.DryRun {
+		return dryRun(ctx, assetsActions)
+	}
+
+	// only deploys of the whole theme are journaled so that deploying a few files
+	// does not replace the journal of an unfinished deploy
+	var journal *deployJournal
+	if len(ctx.Args) == 0 && ctx.Flags.ChangedSince == "" && !ctx.Flags.RetryFailed {
+		journal = startDeployJournal(ctx, assetsActions)
+		defer journal.close()
+	}
 	ctx.StartProgress(len(assetsActions))
+	applyActions(ctx, assetsActions, journal)
+	saveFailures(ctx, "deploy", assetsActions)
+	return nil
+}
+
+// resumeDeploy will perform the operations of an unfinished deploy that have not
+// succeeded yet, according to its journal.
+func resumeDeploy(ctx *cmdutil.Ctx) error {
+	if len(ctx.Args) > 0 || ctx.Flags.ChangedSince != "" {
+		return fmt.Errorf("--resume cannot be used with file names or --changed-since")
+	}
+
+	journal, assetsActions, resumed, err := resumeDeployJournal(ctx)
+	if err != nil {
+		return err
+	}
+	defer journal.close()
+
+	if ctx.Flags.DryRun {
+		ctx.Log.Printf("[%s] resuming deploy, %d files were already deployed", colors.Green(ctx.Env.Name), resumed)
+		return dryRun(ctx, assetsActions)
+	}
+
+	ctx.ResumeTasks(resumed)
+	ctx.StartProgress(len(assetsActions))
+	applyActions(ctx, assetsActions, journal)
+	saveFailures(ctx, "deploy", assetsActions)
+	return nil
+}
+
+// applyActions will perform all of the actions concurrently. settings_data.json
+// is always performed last so that the settings it references already exist.
+// The result of each action is recorded in the journal if there is one and
+// failed actions are recorded with ctx.FailTask.
+func applyActions(ctx *cmdutil.Ctx, assetsActions map[string]file.Op, journal *deployJournal) {
+	var deployGroup sync.WaitGroup
+	apply := func(path string, op file.Op) {
+		err := perform(ctx, path, op, "")
+		if err != nil {
+			ctx.FailTask(path, op)
+		}
+		journal.record(path, op, err)
+	}
 	for path, op := range assetsActions {
 		if path == settingsDataKey {
-			defer perform(ctx, path, op, "")
+			defer apply(path, op)
 			continue
 		}
 		deployGroup.Add(1)
 		go func(path string, op file.Op) {
 			defer deployGroup.Done()
-			perform(ctx, path, op, "")
+			apply(path, op)
 		}(path, op)
 	}
 
 	deployGroup.Wait()
+}
 
+func dryRun(ctx *cmdutil.Ctx, assetsActions map[string]file.Op) error {
+	ctx.DisableSummary()
+
+	paths := []string{}
+	for path := range assetsActions {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	counts := map[file.Op]int{}
+	for _, path := range paths {
+		op := assetsActions[path]
+		counts[op]++
+		switch op {
+		case file.Skip:
+			if ctx.Flags.Verbose {
+				ctx.Log.Printf("[%s] %s %s", colors.Green(ctx.Env.Name), colors.Cyan("Skip"), colors.Blue(path))
+			}
+		case file.Remove:
+			ctx.Log.Printf("[%s] %s %s", colors.Green(ctx.Env.Name), colors.Yellow("Remove"), colors.Blue(path))
+		default:
+			ctx.Log.Printf("[%s] %s %s", colors.Green(ctx.Env.Name), colors.Green("Update"), colors.Blue(path))
+			if ctx.Flags.Merge && isMergeable(path) {
+				asset, err := mergedAsset(ctx, path)
+				if err != nil {
+					ctx.Err("[%s] error merging %s: %s", colors.Green(ctx.Env.Name), colors.Blue(path), err)
+					continue
+				}
+				ctx.Log.Printf("%s", asset.Value)
+			}
+		}
+	}
+
+	ctx.Log.Printf(
+		"[%s] dry run, %s: %d, %s: %d, %s: %d",
+		colors.Green(ctx.Env.Name),
+		colors.Green("Update"), counts[file.Update],
+		colors.Yellow("Remove"), counts[file.Remove],
+		colors.Cyan("No Change"), counts[file.Skip],
+	)
 	return nil
 }
 
+// isMergeable will return true for the files that are customized in the theme editor
+func isMergeable(path string) bool {
+	return path == settingsDataKey || (strings.HasPrefix(path, "templates/") && filepath.Ext(path) == ".json")
+}
+
+// mergedAsset will merge the local version of a file with the version on shopify,
+// using the version that was last deployed or downloaded as the base of the merge.
+// If the file does not exist on shopify yet then the local file is used as is.
+func mergedAsset(ctx *cmdutil.Ctx, path string) (shopify.Asset, error) {
+	local, err := shopify.ReadAsset(ctx.Env, path)
+	if err != nil {
+		return local, err
+	}
+	remote, err := ctx.Client.GetAsset(path)
+	if err == shopify.ErrNotPartOfTheme {
+		return local, nil
+	} else if err != nil {
+		return local, err
+	}
+	return shopify.MergeJSON(loadMergeBase(ctx, path), local, remote)
+}
+
+// mergeBaseDir is the directory in themekitDir that the last deployed or
+// downloaded version of every mergeable file is kept in, for each environment.
+var mergeBaseDir = "merge-base"
+
+// saveMergeBase will keep the version of a mergeable file that is now on shopify
+// and on disk so that the next merge knows which side changed each value.
+func saveMergeBase(ctx *cmdutil.Ctx, asset shopify.Asset) {
+	if mergeBaseDir == "" || !isMergeable(asset.Key) {
+		return
+	}
+	dir := filepath.Join(ctx.Env.Directory, themekitDir, mergeBaseDir, ctx.Env.Name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	asset.Write(dir)
+}
+
+// loadMergeBase will load the last deployed or downloaded version of a file. The
+// asset has no value if there is none.
+func loadMergeBase(ctx *cmdutil.Ctx, path string) shopify.Asset {
+	if mergeBaseDir == "" {
+		return shopify.Asset{Key: path}
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(ctx.Env.Directory, themekitDir, mergeBaseDir, ctx.Env.Name, filepath.FromSlash(path)))
+	return shopify.Asset{Key: path, Value: string(data)}
+}
+
+var (
+	// themekitDir is the directory in the project that themekit keeps its state in
+	// so that it survives restarting a command, like the retry queues of watch and
+	// the journals of deploys.
+	themekitDir = ".themekit"
+	// checksumIndexFile is where the checksums of local files are cached in the
+	// project so that files that have not changed are not read again on every deploy
+	checksumIndexFile = filepath.Join(themekitDir, "checksums.json")
+)
+
+func loadChecksumIndex(ctx *cmdutil.Ctx) *shopify.ChecksumIndex {
+	if checksumIndexFile == "" {
+		return nil
+	}
+	return shopify.LoadChecksumIndex(filepath.Join(ctx.Env.Directory, checksumIndexFile))
+}
+
+// findChecksums will find the checksums of the local files with the checksum
+// index of the project. If the index cannot be saved afterwards the checksums are
+// still returned and the error is logged.
+func findChecksums(ctx *cmdutil.Ctx, paths ...string) ([]shopify.Asset, error) {
+	index := loadChecksumIndex(ctx)
+	assets, err := shopify.FindChecksums(ctx.Env, index, paths...)
+	if err != nil {
+		return assets, err
+	}
+	if err := index.Save(); err != nil {
+		ctx.ErrLog.Printf("[%s] could not save the checksum index: %s", colors.Green(ctx.Env.Name), err)
+	}
+	return assets, nil
+}
+
 func generateActions(ctx *cmdutil.Ctx) (map[string]file.Op, error) {
 	assetsActions := map[string]file.Op{}
 	pathsToChecksums := map[string]string{}
@@ -96,16 +331,11 @@ func generateActions(ctx *cmdutil.Ctx) (map[string]file.Op, error) {
 		pathsToChecksums[remoteAsset.Key] = remoteAsset.Checksum
 	}
 
-	localAssets, err := shopify.FindAssets(ctx.Env, ctx.Args...)
+	localAssets, err := findChecksums(ctx, ctx.Args...)
 	if err != nil {
 		return assetsActions, err
 	}
 
-	problemAssets := compileAssetFilenames(localAssets)
-	if len(problemAssets) > 0 {
-		return assetsActions, compiledAssetWarning(ctx.Env.Name, problemAssets)
-	}
-
 	for _, asset := range localAssets {
 		var path = asset.Key
 		if asset.Checksum != "" && (asset.Checksum == pathsToChecksums[asset.Key]) {
@@ -133,6 +363,22 @@ func compileAssetFilenames(assets []shopify.Asset) (problemAssets []string) {
 	return
 }
 
+// skipCompiledOutputs will leave out local assets that shopify compiles from a
+// liquid source that also exists locally, like assets/app.js next to
+// assets/app.js.liquid. Shopify replaces the liquid source if the compiled asset is
+// uploaded.
+func skipCompiledOutputs(ctx *cmdutil.Ctx, assetsActions map[string]file.Op) {
+	for path, op := range assetsActions {
+		if op != file.Update || !strings.HasPrefix(path, "assets/") || strings.HasSuffix(path, ".liquid") {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(ctx.Env.Directory, filepath.FromSlash(path+".liquid"))); err == nil {
+			delete(assetsActions, path)
+			ctx.Log.Printf("[%s] %s %s, it is compiled by shopify from %s", colors.Green(ctx.Env.Name), colors.Cyan("Skipped"), colors.Blue(path), colors.Blue(path+".liquid"))
+		}
+	}
+}
+
 func compiledAssetWarning(env string, filenames []string) error {
 	var tpl bytes.Buffer
 	compiledFilenameWarning.Execute(&tpl, struct {
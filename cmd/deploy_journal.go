@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/file"
+)
+
+// deployJournalFile is the name of the journal of each environment in themekitDir,
+// it is formatted with the name of the environment.
+var deployJournalFile = "deploy-%s.journal"
+
+const (
+	journalPlan   = "plan"
+	journalDone   = "done"
+	journalFailed = "failed"
+)
+
+// journalEntry is a line in a deploy journal. The first entry is the plan of the
+// deploy and every entry after it is an operation that finished.
+type journalEntry struct {
+	Type    string             `json:"type"`
+	Time    time.Time          `json:"time"`
+	Actions map[string]file.Op `json:"actions,omitempty"`
+	Path    string             `json:"path,omitempty"`
+	Op      file.Op            `json:"op"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// deployJournal is an append-only log of a deploy so that a deploy that was
+// interrupted can be resumed with --resume. The journal is removed once every
+// operation in it has succeeded. All of its methods are safe to call on a nil
+// journal.
+type deployJournal struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	pending int
+	failed  bool
+}
+
+func deployJournalPath(ctx *cmdutil.Ctx) string {
+	if deployJournalFile == "" {
+		return ""
+	}
+	return filepath.Join(ctx.Env.Directory, themekitDir, fmt.Sprintf(deployJournalFile, ctx.Env.Name))
+}
+
+// startDeployJournal will create a new journal with the plan of a deploy. If the
+// journal cannot be written the deploy continues without it.
+func startDeployJournal(ctx *cmdutil.Ctx, actions map[string]file.Op) *deployJournal {
+	path := deployJournalPath(ctx)
+	if path == "" {
+		return nil
+	}
+	journal := &deployJournal{path: path, pending: len(actions)}
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		journal.file, err = os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0644)
+	}
+	if err == nil {
+		err = journal.write(journalEntry{Type: journalPlan, Actions: actions})
+	}
+	if err != nil {
+		ctx.ErrLog.Printf("[%s] could not write deploy journal %s, the deploy cannot be resumed: %s", colors.Green(ctx.Env.Name), colors.Blue(path), err)
+		journal.close()
+		return nil
+	}
+	return journal
+}
+
+// resumeDeployJournal will load the journal of a deploy that did not finish and
+// return the operations that have not succeeded yet and how many already had.
+func resumeDeployJournal(ctx *cmdutil.Ctx) (*deployJournal, map[string]file.Op, int, error) {
+	path := deployJournalPath(ctx)
+	noJournal := fmt.Errorf("[%s] there is no unfinished deploy to resume", colors.Green(ctx.Env.Name))
+	if path == "" {
+		return nil, nil, 0, noJournal
+	}
+
+	journalFile, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil, 0, noJournal
+	} else if err != nil {
+		return nil, nil, 0, err
+	}
+	defer journalFile.Close()
+
+	var plan map[string]file.Op
+	done := map[string]bool{}
+	scanner := bufio.NewScanner(journalFile)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		entry := journalEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// the last line is incomplete if the deploy crashed while writing it
+			continue
+		}
+		switch entry.Type {
+		case journalPlan:
+			plan = entry.Actions
+		case journalDone:
+			done[entry.Path] = true
+		case journalFailed:
+			delete(done, entry.Path)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, 0, err
+	} else if plan == nil {
+		return nil, nil, 0, noJournal
+	}
+
+	remaining := map[string]file.Op{}
+	for path, op := range plan {
+		if !done[path] {
+			remaining[path] = op
+		}
+	}
+
+	journal := &deployJournal{path: path, pending: len(remaining)}
+	if ctx.Flags.DryRun {
+		// a dry run does not write to or remove the journal
+		return journal, remaining, len(plan) - len(remaining), nil
+	} else if journal.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+		return nil, nil, 0, err
+	}
+	return journal, remaining, len(plan) - len(remaining), nil
+}
+
+// record will add a finished operation to the journal
+func (journal *deployJournal) record(path string, op file.Op, err error) {
+	if journal == nil {
+		return
+	}
+	entry := journalEntry{Type: journalDone, Path: path, Op: op}
+	if err != nil {
+		entry.Type = journalFailed
+		entry.Error = err.Error()
+	}
+
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	journal.pending--
+	journal.failed = journal.failed || err != nil
+	journal.writeLocked(entry)
+}
+
+// close will close the journal and remove it if every operation succeeded
+func (journal *deployJournal) close() {
+	if journal == nil || journal.file == nil {
+		return
+	}
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	journal.file.Close()
+	if !journal.failed && journal.pending <= 0 {
+		os.Remove(journal.path)
+	}
+}
+
+func (journal *deployJournal) write(entry journalEntry) error {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	return journal.writeLocked(entry)
+}
+
+func (journal *deployJournal) writeLocked(entry journalEntry) error {
+	entry.Time = time.Now()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = journal.file.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Shopify/themekit/src/file"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+func TestDeployJournalResume(t *testing.T) {
+	dir, cleanup := createStateProject(t, &deployJournalFile, "deploy-%s.journal")
+	defer cleanup()
+
+	ctx, _, _, _, _ := createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.Directory = dir
+	journalPath := filepath.Join(dir, themekitDir, "deploy-development.journal")
+
+	journal := startDeployJournal(ctx, map[string]file.Op{
+		"assets/a.js":   file.Update,
+		"assets/b.js":   file.Update,
+		"assets/c.js":   file.Remove,
+		settingsDataKey: file.Update,
+	})
+	assert.NotNil(t, journal)
+	journal.record("assets/a.js", file.Update, nil)
+	journal.record("assets/b.js", file.Update, fmt.Errorf("server error"))
+	journal.close()
+	_, err := os.Stat(journalPath)
+	assert.Nil(t, err)
+
+	// a crash while writing leaves an incomplete last line
+	f, _ := os.OpenFile(journalPath, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"type":"done","path":"assets/c`)
+	f.Close()
+
+	journal, remaining, resumed, err := resumeDeployJournal(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, resumed)
+	assert.Equal(t, map[string]file.Op{
+		"assets/b.js":   file.Update,
+		"assets/c.js":   file.Remove,
+		settingsDataKey: file.Update,
+	}, remaining)
+
+	journal.record("assets/b.js", file.Update, nil)
+	journal.record("assets/c.js", file.Remove, nil)
+	journal.record(settingsDataKey, file.Update, nil)
+	journal.close()
+	_, err = os.Stat(journalPath)
+	assert.True(t, os.IsNotExist(err))
+
+	_, _, _, err = resumeDeployJournal(ctx)
+	assert.EqualError(t, err, "[development] there is no unfinished deploy to resume")
+}
+
+func TestDeployJournalDisabled(t *testing.T) {
+	ctx, _, _, _, _ := createTestCtx()
+	ctx.Env.Name = "development"
+	assert.Nil(t, startDeployJournal(ctx, map[string]file.Op{"assets/a.js": file.Update}))
+	_, _, _, err := resumeDeployJournal(ctx)
+	assert.NotNil(t, err)
+
+	var journal *deployJournal
+	journal.record("assets/a.js", file.Update, nil)
+	journal.close()
+}
+
+func TestDeployResume(t *testing.T) {
+	dir, cleanup := createStateProject(t, &deployJournalFile, "deploy-%s.journal")
+	defer cleanup()
+	journalPath := filepath.Join(dir, themekitDir, "deploy-development.journal")
+
+	ctx, client, _, _, stdErr := createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.Directory = dir
+	ctx.Flags.NoDelete = true
+	client.On("GetAllAssets").Return([]shopify.Asset{}, nil)
+	client.On("UpdateAsset", mock.MatchedBy(func(asset shopify.Asset) bool { return asset.Key == "assets/a.js" }), "").Return(nil).Once()
+	client.On("UpdateAsset", mock.MatchedBy(func(asset shopify.Asset) bool { return asset.Key == "assets/b.js" }), "").Return(fmt.Errorf("server error")).Once()
+	assert.Nil(t, deploy(ctx))
+	assert.Contains(t, stdErr.String(), "server error")
+	_, err := os.Stat(journalPath)
+	assert.Nil(t, err, "the journal is kept when a deploy has errors")
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.Directory = dir
+	ctx.Args = []string{"assets/a.js"}
+	client.On("GetAllAssets").Return([]shopify.Asset{}, nil)
+	client.On("UpdateAsset", mock.Anything, "").Return(nil).Once()
+	assert.Nil(t, deploy(ctx))
+	journal, remaining, _, err := resumeDeployJournal(ctx)
+	assert.Nil(t, err, "deploying some files does not replace the journal")
+	assert.Equal(t, map[string]file.Op{"assets/b.js": file.Update}, remaining)
+	journal.close()
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.Directory = dir
+	ctx.Flags.Resume = true
+	client.On("UpdateAsset", mock.MatchedBy(func(asset shopify.Asset) bool { return asset.Key == "assets/b.js" }), "").Return(nil).Once()
+	assert.Nil(t, deploy(ctx))
+	client.AssertExpectations(t)
+	client.AssertNotCalled(t, "GetAllAssets")
+	_, err = os.Stat(journalPath)
+	assert.True(t, os.IsNotExist(err), "the journal is removed once the deploy succeeds")
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.Directory = dir
+	ctx.Flags.Resume = true
+	assert.EqualError(t, deploy(ctx), "[development] there is no unfinished deploy to resume")
+
+	ctx.Args = []string{"assets/a.js"}
+	assert.NotNil(t, deploy(ctx))
+}
+
+func TestDeployResumeDryRun(t *testing.T) {
+	dir, cleanup := createStateProject(t, &deployJournalFile, "deploy-%s.journal")
+	defer cleanup()
+
+	ctx, _, _, stdOut, _ := createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.Directory = dir
+	journal := startDeployJournal(ctx, map[string]file.Op{"assets/a.js": file.Update, "assets/b.js": file.Update})
+	journal.record("assets/a.js", file.Update, nil)
+	journal.close()
+
+	ctx.Flags.Resume = true
+	ctx.Flags.DryRun = true
+	assert.Nil(t, deploy(ctx))
+	assert.Contains(t, stdOut.String(), "1 files were already deployed")
+	assert.Contains(t, stdOut.String(), "assets/b.js")
+	assert.NotContains(t, stdOut.String(), "assets/a.js")
+
+	ctx.Flags.DryRun = false
+	journal, _, resumed, err := resumeDeployJournal(ctx)
+	assert.Nil(t, err, "a dry run does not change the journal")
+	assert.Equal(t, 1, resumed)
+	journal.close()
+
+	// the journal is kept when there is nothing left to resume
+	journal = startDeployJournal(ctx, map[string]file.Op{"assets/a.js": file.Update})
+	journal.record("assets/a.js", file.Update, nil)
+	journal.file.Close()
+	ctx.Flags.DryRun = true
+	assert.Nil(t, deploy(ctx))
+	_, err = os.Stat(filepath.Join(dir, themekitDir, "deploy-development.journal"))
+	assert.Nil(t, err)
+}
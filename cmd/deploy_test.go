@@ -3,8 +3,11 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -14,6 +17,31 @@ import (
 	"github.com/Shopify/themekit/src/shopify"
 )
 
+func TestMain(m *testing.M) {
+	// the checksum index, deploy journals and failed files are only saved by the
+	// tests that check them so that the test projects are not changed
+	checksumIndexFile = ""
+	deployJournalFile = ""
+	failedFile = ""
+	mergeBaseDir = ""
+	os.Exit(m.Run())
+}
+
+// createStateProject will create a temporary project with two assets and enable
+// saving the state file so that it is written inside of that project.
+func createStateProject(t *testing.T, stateFile *string, name string) (string, func()) {
+	dir, err := ioutil.TempDir("", "themekit-state")
+	assert.Nil(t, err)
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "assets"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "assets", "a.js"), []byte("a"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "assets", "b.js"), []byte("b"), 0644))
+	*stateFile = name
+	return dir, func() {
+		*stateFile = ""
+		os.RemoveAll(dir)
+	}
+}
+
 func TestUploadSingleFile(t *testing.T) {
 	ctx, client, _, _, _ := createTestCtx()
 	ctx.Args = []string{"templates/layout.liquid"}
@@ -108,16 +136,127 @@ func TestReplace(t *testing.T) {
 	}
 }
 
+func TestDeployMerge(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "merge")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "config"), 0755)
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "config", "settings_data.json"), []byte(`{"current": {"color": "red", "font": "serif"}}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "templates", "index.json"), []byte(`{"order": ["hero"]}`), 0644)
+
+	ctx, client, _, _, _ := createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Flags.Merge = true
+	ctx.Flags.NoDelete = true
+	client.On("GetAllAssets").Return([]shopify.Asset{{Key: "config/settings_data.json"}}, nil)
+	client.On("GetAsset", "config/settings_data.json").Return(shopify.Asset{Key: "config/settings_data.json", Value: `{"current": {"color": "blue"}}`}, nil)
+	client.On("GetAsset", "templates/index.json").Return(shopify.Asset{}, shopify.ErrNotPartOfTheme)
+	client.On("UpdateAsset", mock.MatchedBy(func(a shopify.Asset) bool {
+		return a.Key == "config/settings_data.json" && a.Value == "{\n  \"current\": {\n    \"color\": \"blue\",\n    \"font\": \"serif\"\n  }\n}\n"
+	}), "").Return(nil).Once()
+	client.On("UpdateAsset", mock.MatchedBy(func(a shopify.Asset) bool {
+		return a.Key == "templates/index.json" && a.Value == `{"order": ["hero"]}`
+	}), "").Return(nil).Once()
+	assert.Nil(t, deploy(ctx))
+	client.AssertExpectations(t)
+
+	ctx, client, _, _, stdErr := createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Flags.Merge = true
+	ctx.Args = []string{"config/settings_data.json"}
+	client.On("GetAllAssets").Return([]shopify.Asset{}, nil)
+	client.On("GetAsset", "config/settings_data.json").Return(shopify.Asset{}, fmt.Errorf("server error"))
+	assert.Nil(t, deploy(ctx))
+	assert.Contains(t, stdErr.String(), "error loading config/settings_data.json: server error")
+}
+
+func TestDeployMergeWithBase(t *testing.T) {
+	defer func() { mergeBaseDir = "" }()
+	mergeBaseDir = "merge-base"
+
+	dir, _ := ioutil.TempDir("", "merge")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "config"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "config", "settings_data.json"), []byte(`{"current": {"color": "red", "font": "serif"}}`), 0644)
+
+	ctx, client, _, _, _ := createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Args = []string{"config/settings_data.json"}
+	client.On("GetAllAssets").Return([]shopify.Asset{}, nil)
+	client.On("UpdateAsset", mock.Anything, "").Return(nil)
+	assert.Nil(t, deploy(ctx))
+	base := loadMergeBase(ctx, "config/settings_data.json")
+	assert.Contains(t, base.Value, `"color": "red"`)
+
+	// the color is changed locally and the font is changed in the theme editor
+	ioutil.WriteFile(filepath.Join(dir, "config", "settings_data.json"), []byte(`{"current": {"color": "green", "font": "serif"}}`), 0644)
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Args = []string{"config/settings_data.json"}
+	ctx.Flags.Merge = true
+	client.On("GetAllAssets").Return([]shopify.Asset{}, nil)
+	client.On("GetAsset", "config/settings_data.json").Return(shopify.Asset{Key: "config/settings_data.json", Value: `{"current": {"color": "red", "font": "sans"}}`}, nil)
+	client.On("UpdateAsset", mock.MatchedBy(func(a shopify.Asset) bool {
+		return a.Value == "{\n  \"current\": {\n    \"color\": \"green\",\n    \"font\": \"sans\"\n  }\n}\n"
+	}), "").Return(nil).Once()
+	assert.Nil(t, deploy(ctx))
+	client.AssertExpectations(t)
+	assert.Contains(t, loadMergeBase(ctx, "config/settings_data.json").Value, `"font": "sans"`)
+}
+
+func TestDeployDryRun(t *testing.T) {
+	ctx, client, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	ctx.Flags.DryRun = true
+	client.On("GetAllAssets").Return([]shopify.Asset{{Key: "assets/logo.png"}, {Key: "assets/app.js", Checksum: "d41d8cd98f00b204e9800998ecf8427e"}}, nil)
+	assert.Nil(t, deploy(ctx))
+	assert.Contains(t, stdOut.String(), "Remove assets/logo.png")
+	assert.Contains(t, stdOut.String(), "Update config/settings_data.json")
+	assert.NotContains(t, stdOut.String(), "Skip assets/app.js")
+	assert.Contains(t, stdOut.String(), "dry run, Update: 1, Remove: 1, No Change: 1")
+	client.AssertNotCalled(t, "UpdateAsset", mock.Anything, mock.Anything)
+	client.AssertNotCalled(t, "DeleteAsset", mock.Anything)
+
+	dir, _ := ioutil.TempDir("", "merge")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "config"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "config", "settings_data.json"), []byte(`{"current": {"font": "serif"}}`), 0644)
+
+	ctx, client, _, stdOut, stdErr := createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Flags.DryRun = true
+	ctx.Flags.Merge = true
+	ctx.Flags.Verbose = true
+	client.On("GetAllAssets").Return([]shopify.Asset{}, nil)
+	client.On("GetAsset", "config/settings_data.json").Return(shopify.Asset{Value: `{"current": {"color": "blue"}}`}, nil).Once()
+	assert.Nil(t, deploy(ctx))
+	assert.Contains(t, stdOut.String(), "\"color\": \"blue\"")
+	assert.Contains(t, stdOut.String(), "\"font\": \"serif\"")
+
+	client.On("GetAsset", "config/settings_data.json").Return(shopify.Asset{Value: `{`}, nil)
+	assert.Nil(t, deploy(ctx))
+	assert.Contains(t, stdErr.String(), "error merging config/settings_data.json")
+}
+
+func TestIsMergeable(t *testing.T) {
+	assert.True(t, isMergeable("config/settings_data.json"))
+	assert.True(t, isMergeable("templates/index.json"))
+	assert.True(t, isMergeable("templates/customers/account.json"))
+	assert.False(t, isMergeable("templates/index.liquid"))
+	assert.False(t, isMergeable("config/settings_schema.json"))
+}
+
 func TestGenerateActions(t *testing.T) {
 	ctx, client, _, _, _ := createTestCtx()
 	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
-	client.On("GetAllAssets").Return([]shopify.Asset{{Key: "assets/logo.png"}}, nil)
+	client.On("GetAllAssets").Return([]shopify.Asset{{Key: "assets/logo.png"}, {Key: "assets/theme.css.liquid", Compiled: "assets/theme.css"}}, nil)
 	actions, err := generateActions(ctx)
 	assert.Nil(t, err)
 	assert.Equal(t, actions["assets/logo.png"], file.Remove)
+	assert.Equal(t, actions["assets/theme.css.liquid"], file.Remove)
 	assert.Equal(t, actions["config/settings_data.json"], file.Update)
 	assert.Equal(t, actions["assets/app.js"], file.Update)
-	assert.Equal(t, len(actions), 3)
+	assert.Equal(t, len(actions), 4)
 	_, found := actions["assets/.gitkeep"]
 	assert.False(t, found)
 
@@ -140,13 +279,52 @@ func TestGenerateActions(t *testing.T) {
 	ctx.Env.Directory = filepath.Join("_testdata", "badprojectdir")
 	client.On("GetAllAssets").Return([]shopify.Asset{}, nil)
 	actions, err = generateActions(ctx)
-	assert.NotNil(t, err)
-	var tpl bytes.Buffer
-	compiledFilenameWarning.Execute(&tpl, struct {
-		EnvName   string
-		FileNames []string
-	}{EnvName: colors.Yellow("development"), FileNames: []string{colors.Yellow("assets/app.js") + colors.Blue(" conflicts with ") + colors.Yellow("assets/app.js.liquid")}})
-	assert.Equal(t, tpl.String(), err.Error())
+	assert.Nil(t, err)
+	assert.Equal(t, file.Update, actions["assets/app.js"])
+	assert.Equal(t, file.Update, actions["assets/app.js.liquid"])
+}
+
+func TestGenerateActionsChecksumIndex(t *testing.T) {
+	defer func(path string) { checksumIndexFile = path }(checksumIndexFile)
+	checksumIndexFile = filepath.Join(".themekit", "checksums.json")
+
+	dir, _ := ioutil.TempDir("", "index")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "assets"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "assets", "app.js"), []byte(""), 0644)
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "assets", "app.js"), past, past)
+
+	ctx, client, _, _, _ := createTestCtx()
+	ctx.Env.Directory = dir
+	client.On("GetAllAssets").Return([]shopify.Asset{{Key: "assets/app.js", Checksum: "d41d8cd98f00b204e9800998ecf8427e"}}, nil)
+	actions, err := generateActions(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]file.Op{"assets/app.js": file.Skip}, actions)
+
+	index := loadChecksumIndex(ctx)
+	checksum, err := index.Checksum(ctx.Env, "assets/app.js", fileInfo(t, filepath.Join(dir, "assets", "app.js")))
+	assert.Nil(t, err)
+	assert.Equal(t, "d41d8cd98f00b204e9800998ecf8427e", checksum)
+	_, err = os.Stat(filepath.Join(dir, ".themekit", "checksums.json"))
+	assert.Nil(t, err)
+
+	// the checksums are still found when the index cannot be saved
+	os.RemoveAll(filepath.Join(dir, ".themekit"))
+	ioutil.WriteFile(filepath.Join(dir, ".themekit"), []byte(""), 0644)
+	ctx, _, _, _, stdErr := createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.Directory = dir
+	assets, err := findChecksums(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(assets))
+	assert.Contains(t, stdErr.String(), "[development] could not save the checksum index")
+}
+
+func fileInfo(t *testing.T, path string) os.FileInfo {
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	return info
 }
 
 func TestCompileAssetFilenames(t *testing.T) {
@@ -165,6 +343,39 @@ func TestCompileAssetFilenames(t *testing.T) {
 	assert.Equal(t, expected, compileAssetFilenames(input))
 }
 
+func TestSkipCompiledOutputs(t *testing.T) {
+	ctx, _, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "badprojectdir")
+	actions := map[string]file.Op{
+		"assets/app.js":        file.Update,
+		"assets/app.js.liquid": file.Update,
+		"assets/other.js":      file.Update,
+	}
+	skipCompiledOutputs(ctx, actions)
+	assert.Equal(t, map[string]file.Op{
+		"assets/app.js.liquid": file.Update,
+		"assets/other.js":      file.Update,
+	}, actions)
+	assert.Contains(t, stdOut.String(), "Skipped assets/app.js, it is compiled by shopify from assets/app.js.liquid")
+
+	ctx, client, _, _, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "badprojectdir")
+	ctx.Args = []string{"assets/app.js"}
+	client.On("GetAllAssets").Return([]shopify.Asset{}, nil)
+	assert.Nil(t, deploy(ctx))
+	client.AssertNotCalled(t, "UpdateAsset", mock.Anything, mock.Anything)
+
+	// a full deploy of a project with both files only uploads the liquid source
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "badprojectdir")
+	client.On("GetAllAssets").Return([]shopify.Asset{}, nil)
+	client.On("UpdateAsset", mock.Anything, "").Return(nil)
+	assert.Nil(t, deploy(ctx))
+	client.AssertCalled(t, "UpdateAsset", mock.MatchedBy(func(asset shopify.Asset) bool { return asset.Key == "assets/app.js.liquid" }), "")
+	client.AssertCalled(t, "UpdateAsset", mock.MatchedBy(func(asset shopify.Asset) bool { return asset.Key == "config/settings_data.json" }), "")
+	client.AssertNotCalled(t, "UpdateAsset", mock.MatchedBy(func(asset shopify.Asset) bool { return asset.Key == "assets/app.js" }), "")
+}
+
 func TestCompiledAssetWarning(t *testing.T) {
 	filenames := []string{
 		colors.Yellow("assets/app.js") + colors.Blue(" conflicts with ") + colors.Yellow("assets/app.js.liquid"),
@@ -1,18 +1,30 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/spf13/cobra"
 
 	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
 	"github.com/Shopify/themekit/src/file"
 	"github.com/Shopify/themekit/src/shopify"
 )
 
+var (
+	// confirmInput is where answers to confirmation prompts are read from
+	confirmInput = bufio.NewReader(os.Stdin)
+	// confirmMu makes sure that only one environment prompts at a time
+	confirmMu sync.Mutex
+)
+
 var downloadCmd = &cobra.Command{
 	Use:   "download <filenames>",
 	Short: "Download one or all of the theme files",
@@ -20,6 +32,15 @@ var downloadCmd = &cobra.Command{
  If no filenames are provided then download will download every file in the project
  and write them to disk.
 
+ Pass the --mirror flag to also remove local files in the theme folders that no
+ longer exist on shopify, so that your project matches the theme exactly. Ignored
+ files are never removed. You will be asked to confirm before anything is removed.
+ Pass the --dry-run flag to list what would be downloaded and removed without
+ changing anything.
+
+ The files that fail to download are saved in .themekit/failed-download-<env>.json.
+ Pass --retry-failed to download only those files again.
+
  For more information, refer to https://shopify.dev/tools/theme-kit/command-reference#download.
  `,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -39,7 +60,19 @@ var downloadCmd = &cobra.Command{
 func download(ctx *cmdutil.Ctx) error {
 	var downloadGroup sync.WaitGroup
 
-	assets, err := filesToDownload(ctx)
+	if ctx.Flags.Mirror && len(ctx.Args) > 0 {
+		return fmt.Errorf("--mirror cannot be used when downloading specific files")
+	} else if ctx.Flags.Mirror && ctx.Flags.RetryFailed {
+		return fmt.Errorf("--mirror cannot be used with --retry-failed")
+	}
+
+	var assets map[string]file.Op
+	var err error
+	if ctx.Flags.RetryFailed {
+		assets, err = loadFailures(ctx, "download")
+	} else {
+		assets, err = filesToDownload(ctx)
+	}
 	if err != nil {
 		return err
 	}
@@ -48,17 +81,100 @@ func download(ctx *cmdutil.Ctx) error {
 		return fmt.Errorf("No files to download")
 	}
 
-	ctx.StartProgress(len(assets))
+	prune := []string{}
+	if ctx.Flags.Mirror {
+		if prune, err = filesToPrune(ctx, assets); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Flags.DryRun {
+		return downloadDryRun(ctx, assets, prune)
+	}
+
+	if len(prune) > 0 {
+		for _, path := range prune {
+			ctx.Log.Printf("[%s] %s %s", colors.Green(ctx.Env.Name), colors.Yellow("Remove"), colors.Blue(path))
+		}
+		if !confirm(ctx, fmt.Sprintf("[%s] Remove %d local files that do not exist on shopify?", colors.Green(ctx.Env.Name), len(prune))) {
+			return fmt.Errorf("[%s] download cancelled", colors.Green(ctx.Env.Name))
+		}
+	}
+
+	ctx.StartProgress(len(assets) + len(prune))
 	for asset, op := range assets {
 		downloadGroup.Add(1)
 		go func(path string, op file.Op) {
 			defer downloadGroup.Done()
-			perform(ctx, path, op, "")
+			if err := perform(ctx, path, op, ""); err != nil {
+				ctx.FailTask(path, op)
+			}
 		}(asset, op)
 	}
 
 	downloadGroup.Wait()
 
+	for _, path := range prune {
+		if err := os.Remove(filepath.Join(ctx.Env.Directory, filepath.FromSlash(path))); err != nil {
+			ctx.Err("[%s] error removing %s: %s", colors.Green(ctx.Env.Name), colors.Blue(path), err)
+		} else if ctx.Flags.Verbose {
+			ctx.Log.Printf("[%s] Removed %s", colors.Green(ctx.Env.Name), colors.Blue(path))
+		}
+		ctx.DoneTask(file.Remove)
+	}
+
+	saveFailures(ctx, "download", assets)
+	return nil
+}
+
+// filesToPrune will return the local files that are not in the set of files on
+// shopify. Only files in the theme folders that are not ignored are returned.
+func filesToPrune(ctx *cmdutil.Ctx, remote map[string]file.Op) ([]string, error) {
+	prune := []string{}
+	localAssets, err := findChecksums(ctx)
+	if err != nil {
+		return prune, err
+	}
+	for _, asset := range localAssets {
+		if _, ok := remote[asset.Key]; !ok {
+			prune = append(prune, asset.Key)
+		}
+	}
+	sort.Strings(prune)
+	return prune, nil
+}
+
+func downloadDryRun(ctx *cmdutil.Ctx, assets map[string]file.Op, prune []string) error {
+	ctx.DisableSummary()
+
+	paths := []string{}
+	for path := range assets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	skipped := 0
+	for _, path := range paths {
+		if assets[path] == file.Skip {
+			skipped++
+			if ctx.Flags.Verbose {
+				ctx.Log.Printf("[%s] %s %s", colors.Green(ctx.Env.Name), colors.Cyan("Skip"), colors.Blue(path))
+			}
+			continue
+		}
+		ctx.Log.Printf("[%s] %s %s", colors.Green(ctx.Env.Name), colors.Blue("Download"), colors.Blue(path))
+	}
+	for _, path := range prune {
+		ctx.Log.Printf("[%s] %s %s", colors.Green(ctx.Env.Name), colors.Yellow("Remove"), colors.Blue(path))
+	}
+
+	ctx.Log.Printf(
+		"[%s] dry run, %s: %d, %s: %d, %s: %d",
+		colors.Green(ctx.Env.Name),
+		colors.Blue("Download"), len(paths)-skipped,
+		colors.Yellow("Remove"), len(prune),
+		colors.Cyan("No Change"), skipped,
+	)
 	return nil
 }
 
@@ -109,3 +225,14 @@ func downloadFileAction(ctx *cmdutil.Ctx, asset shopify.Asset) file.Op {
 	}
 	return op
 }
+
+// confirm will ask the user a yes or no question and return true if they answer
+// yes. Anything else, including no input, is taken as a no.
+func confirm(ctx *cmdutil.Ctx, question string) bool {
+	confirmMu.Lock()
+	defer confirmMu.Unlock()
+	ctx.Log.Printf("%s [y/N]", question)
+	answer, _ := confirmInput.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -1,7 +1,12 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -95,3 +100,74 @@ func TestFilesToDownloadFileAction(t *testing.T) {
 	op = downloadFileAction(ctx, shopify.Asset{Key: "assets/app.js"})
 	assert.Equal(t, file.Get, op)
 }
+
+func TestDownloadMirror(t *testing.T) {
+	defer func(input *bufio.Reader) { confirmInput = input }(confirmInput)
+
+	dir, _ := ioutil.TempDir("", "mirror")
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"assets/app.js", "snippets/old.liquid", "snippets/.DS_Store", "README.md"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte(""), 0644)
+	}
+	remote := []shopify.Asset{{Key: "assets/app.js", Checksum: "d41d8cd98f00b204e9800998ecf8427e"}, {Key: "layout/theme.liquid"}}
+
+	ctx, client, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Flags.Mirror = true
+	ctx.Flags.DryRun = true
+	client.On("GetAllAssets").Return(remote, nil)
+	assert.Nil(t, download(ctx))
+	assert.Contains(t, stdOut.String(), "Download layout/theme.liquid")
+	assert.Contains(t, stdOut.String(), "Remove snippets/old.liquid")
+	assert.Contains(t, stdOut.String(), "dry run, Download: 1, Remove: 1, No Change: 1")
+	client.AssertNotCalled(t, "GetAsset", "layout/theme.liquid")
+	_, err := os.Stat(filepath.Join(dir, "snippets", "old.liquid"))
+	assert.Nil(t, err)
+
+	confirmInput = bufio.NewReader(strings.NewReader("n\n"))
+	ctx, client, _, stdOut, _ = createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Flags.Mirror = true
+	client.On("GetAllAssets").Return(remote, nil)
+	err = download(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "download cancelled")
+	}
+	assert.Contains(t, stdOut.String(), "Remove 1 local files that do not exist on shopify? [y/N]")
+	client.AssertNotCalled(t, "GetAsset", "layout/theme.liquid")
+
+	confirmInput = bufio.NewReader(strings.NewReader("y\n"))
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Flags.Mirror = true
+	client.On("GetAllAssets").Return(remote, nil)
+	client.On("GetAsset", "layout/theme.liquid").Return(shopify.Asset{Key: "layout/theme.liquid", Value: "{{ content_for_layout }}"}, nil)
+	assert.Nil(t, download(ctx))
+	_, err = os.Stat(filepath.Join(dir, "snippets", "old.liquid"))
+	assert.True(t, os.IsNotExist(err))
+	for _, name := range []string{"assets/app.js", "layout/theme.liquid", "snippets/.DS_Store", "README.md"} {
+		_, err = os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		assert.Nil(t, err, name)
+	}
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Flags.Mirror = true
+	ctx.Args = []string{"assets/app.js"}
+	err = download(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "--mirror cannot be used when downloading specific files")
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	defer func(input *bufio.Reader) { confirmInput = input }(confirmInput)
+	ctx, _, _, stdOut, _ := createTestCtx()
+
+	for answer, expected := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		confirmInput = bufio.NewReader(strings.NewReader(answer))
+		assert.Equal(t, expected, confirm(ctx, "Are you sure?"), answer)
+	}
+	assert.Contains(t, stdOut.String(), "Are you sure? [y/N]")
+}
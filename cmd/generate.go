@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/file"
+	"github.com/Shopify/themekit/src/generate"
+	"github.com/Shopify/themekit/src/locales"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate new sections, snippets, templates and locales",
+	Long: `Generate will create a new theme file from a template so that every file
+ starts out with the same conventions. Translation keys that the new file uses
+ with the t filter are added to the default locale.
+
+ The templates can be overridden in a project by adding a <kind>.tmpl file to the
+ .themekit/generators directory in the project, for example
+ .themekit/generators/section.tmpl. Templates use {{% .Handle %}}, {{% .Key %}},
+ {{% .Title %}} and {{% .Name %}} for the name of the file being generated.
+ `,
+}
+
+var (
+	generateSectionCmd  = generatorCmd(generate.Section, "Generate a section with a schema and presets")
+	generateSnippetCmd  = generatorCmd(generate.Snippet, "Generate a snippet")
+	generateTemplateCmd = generatorCmd(generate.Template, "Generate a template")
+	generateLocaleCmd   = generatorCmd(generate.Locale, "Generate a locale with the keys of the default locale")
+)
+
+func generatorCmd(kind, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   kind + " <name>",
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmdutil.ForLocal(flags, args, func(ctx *cmdutil.Ctx) error {
+				return generateFile(ctx, kind)
+			})
+		},
+	}
+}
+
+func generateFile(ctx *cmdutil.Ctx, kind string) error {
+	if ctx.Env.ReadOnly {
+		return fmt.Errorf("[%s] environment is readonly", colors.Green(ctx.Env.Name))
+	} else if len(ctx.Args) != 1 {
+		return fmt.Errorf("please specify the name of the %s to generate", kind)
+	}
+
+	data := generate.NewData(ctx.Args[0])
+	if data.Handle == "" {
+		return fmt.Errorf("%s is not a valid %s name", ctx.Args[0], kind)
+	}
+
+	key, err := generate.Path(kind, data)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(ctx.Env.Directory, filepath.FromSlash(key))
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("[%s] %s already exists", colors.Green(ctx.Env.Name), colors.Blue(key))
+	}
+
+	contents, err := generate.Render(ctx.Env.Directory, kind, data)
+	if err != nil {
+		return err
+	}
+
+	if kind == generate.Locale {
+		if contents, err = fillLocale(ctx, key, contents); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	} else if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+		return err
+	}
+	ctx.Log.Printf("[%s] %s %s", colors.Green(ctx.Env.Name), colors.Green("Created"), colors.Blue(key))
+	ctx.DoneTask(file.Update)
+
+	if kind == generate.Locale {
+		return nil
+	}
+	return registerTranslations(ctx, generate.TranslationKeys(contents), data.Title)
+}
+
+// fillLocale will add every key of the default locale to a new locale with an
+// empty value so that they can be found with 'theme locales check'.
+func fillLocale(ctx *cmdutil.Ctx, key string, contents []byte) ([]byte, error) {
+	locale, err := locales.Parse(key, string(contents))
+	if err != nil {
+		return nil, err
+	}
+
+	def, err := defaultLocale(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for defKey := range def.Flatten() {
+		if !locale.Has(defKey) {
+			locale.Set(defKey, "")
+		}
+	}
+	return locale.Marshal()
+}
+
+// registerTranslations will add any keys that do not exist yet to the default
+// locale with the title of the generated file as the value.
+func registerTranslations(ctx *cmdutil.Ctx, keys []string, title string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	def, err := defaultLocale(ctx)
+	if err != nil {
+		return err
+	}
+
+	added := []string{}
+	for _, key := range keys {
+		if !def.Has(key) {
+			def.Set(key, title)
+			added = append(added, key)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	data, err := def.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := (shopify.Asset{Key: def.Key, Value: string(data)}).Write(ctx.Env.Directory); err != nil {
+		return err
+	}
+	for _, key := range added {
+		ctx.Log.Printf("[%s] Added translation %s to %s", colors.Green(ctx.Env.Name), colors.Yellow(key), colors.Blue(def.Key))
+	}
+	return nil
+}
+
+// defaultLocale will load the default locale of the project or create an empty
+// en.default locale if there is none.
+func defaultLocale(ctx *cmdutil.Ctx) (locales.Locale, error) {
+	if _, err := os.Stat(filepath.Join(ctx.Env.Directory, "locales")); os.IsNotExist(err) {
+		return locales.New("locales/en.default.json"), nil
+	}
+
+	set, err := loadLocales(ctx, false)
+	if err != nil {
+		return locales.Locale{}, err
+	}
+
+	def, err := locales.FindDefault(set, false)
+	if err == locales.ErrNoDefaultLocale {
+		return locales.New("locales/en.default.json"), nil
+	}
+	return def, err
+}
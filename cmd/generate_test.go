@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Shopify/themekit/src/generate"
+)
+
+func TestGenerateFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "generate")
+	defer os.RemoveAll(dir)
+
+	ctx, _, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Args = []string{"Hero Banner"}
+	assert.Nil(t, generateFile(ctx, generate.Section))
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "sections", "hero-banner.liquid"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(contents), "{% schema %}")
+	assert.Contains(t, stdOut.String(), "Created sections/hero-banner.liquid")
+	assert.Contains(t, stdOut.String(), "Added translation sections.hero_banner.title to locales/en.default.json")
+	locale, _ := ioutil.ReadFile(filepath.Join(dir, "locales", "en.default.json"))
+	assert.Equal(t, "{\n  \"sections\": {\n    \"hero_banner\": {\n      \"title\": \"Hero Banner\"\n    }\n  }\n}\n", string(locale))
+
+	err = generateFile(ctx, generate.Section)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "sections/hero-banner.liquid already exists")
+	}
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Args = []string{"price"}
+	assert.Nil(t, generateFile(ctx, generate.Snippet))
+	locale, _ = ioutil.ReadFile(filepath.Join(dir, "locales", "en.default.json"))
+	assert.Contains(t, string(locale), "\"hero_banner\"")
+	assert.Contains(t, string(locale), "\"price\": {\n      \"title\": \"Price\"")
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Args = []string{"fr"}
+	assert.Nil(t, generateFile(ctx, generate.Locale))
+	locale, _ = ioutil.ReadFile(filepath.Join(dir, "locales", "fr.json"))
+	assert.Contains(t, string(locale), "\"hero_banner\": {\n      \"title\": \"\"")
+	assert.Contains(t, string(locale), "\"price\": {\n      \"title\": \"\"")
+
+	override := filepath.Join(dir, filepath.FromSlash(generate.OverrideDir))
+	os.MkdirAll(override, 0755)
+	ioutil.WriteFile(filepath.Join(override, "template.tmpl"), []byte("<h1>{{% .Title %}}</h1>"), 0644)
+	ctx, _, _, stdOut, _ = createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Args = []string{"page.contact"}
+	assert.Nil(t, generateFile(ctx, generate.Template))
+	contents, _ = ioutil.ReadFile(filepath.Join(dir, "templates", "page.contact.liquid"))
+	assert.Equal(t, "<h1>Page Contact</h1>", string(contents))
+	assert.NotContains(t, stdOut.String(), "Added translation")
+
+	testcases := []struct {
+		args     []string
+		readonly bool
+		err      string
+	}{
+		{err: "please specify the name of the section to generate"},
+		{args: []string{"!!!"}, err: "!!! is not a valid section name"},
+		{args: []string{"hero"}, readonly: true, err: "environment is readonly"},
+	}
+	for _, testcase := range testcases {
+		ctx, _, _, _, _ = createTestCtx()
+		ctx.Env.Directory = dir
+		ctx.Env.ReadOnly = testcase.readonly
+		ctx.Args = testcase.args
+		err := generateFile(ctx, generate.Section)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), testcase.err)
+		}
+	}
+}
+
+func TestDefaultLocale(t *testing.T) {
+	ctx, _, _, _, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "localesdir")
+	def, err := defaultLocale(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "locales/en.default.json", def.Key)
+	assert.Equal(t, "Thanks", def.Flatten()["general.footer"])
+
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	def, err = defaultLocale(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(def.Flatten()))
+}
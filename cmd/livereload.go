@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/file"
+)
+
+// liveReloadScript is served at /livereload.js. It reloads the page when files
+// change and swaps stylesheets in place if only css changed.
+const liveReloadScript = `(function() {
+  var origin = new URL(document.currentScript.src).origin;
+  var source = new EventSource(origin + "/events");
+  source.addEventListener("reload", function(event) {
+    var data = JSON.parse(event.data);
+    if (!data.css) {
+      window.location.reload();
+      return;
+    }
+    var links = document.querySelectorAll('link[rel="stylesheet"]');
+    data.files.forEach(function(file) {
+      var name = file.split("/").pop().replace(/\.liquid$/, "");
+      if (/\.scss$/.test(name)) {
+        name += ".css";
+      }
+      for (var i = 0; i < links.length; i++) {
+        var url = new URL(links[i].href, window.location.href);
+        if (url.pathname.split("/").pop() === name) {
+          url.searchParams.set("livereload", Date.now());
+          links[i].href = url.toString();
+        }
+      }
+    });
+  });
+})();
+`
+
+// liveReload is a notifyAdapter that serves server sent events to browsers so
+// that they reload once after a batch of files is uploaded.
+type liveReload struct {
+	*notifyBatch
+	server  *http.Server
+	mu      sync.Mutex
+	clients map[chan []byte]bool
+}
+
+func newLiveReload(delay time.Duration) *liveReload {
+	reloader := &liveReload{clients: map[chan []byte]bool{}}
+	reloader.notifyBatch = newNotifyBatch(delay, reloader.reload)
+	return reloader
+}
+
+// startLiveReload will start a live reload server listening on addr
+func startLiveReload(addr string) (*liveReload, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not start live reload server: %s", err)
+	}
+	reloader := newLiveReload(notifyDelay)
+	reloader.server = &http.Server{Handler: reloader.handler()}
+	go reloader.server.Serve(listener)
+	return reloader, nil
+}
+
+// Close will stop the server and disconnect all browsers
+func (reloader *liveReload) Close() error {
+	if reloader.server == nil {
+		return nil
+	}
+	return reloader.server.Close()
+}
+
+func (reloader *liveReload) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/livereload.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Header().Set("Cache-Control", "no-cache")
+		fmt.Fprint(w, liveReloadScript)
+	})
+	mux.HandleFunc("/events", reloader.serveEvents)
+	return mux
+}
+
+func (reloader *liveReload) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	messages := make(chan []byte, 1)
+	reloader.mu.Lock()
+	reloader.clients[messages] = true
+	reloader.mu.Unlock()
+	defer func() {
+		reloader.mu.Lock()
+		delete(reloader.clients, messages)
+		reloader.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case message := <-messages:
+			fmt.Fprintf(w, "event: reload\ndata: %s\n\n", message)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// reload will tell every connected browser to reload for a batch of changed files
+func (reloader *liveReload) reload(ctx *cmdutil.Ctx, events []file.Event) {
+	files := []string{}
+	for _, event := range events {
+		files = append(files, event.Path)
+	}
+	message, _ := json.Marshal(map[string]interface{}{"files": files, "css": onlyStylesheets(files)})
+	fullReload, _ := json.Marshal(map[string]interface{}{"files": files, "css": false})
+
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+	for client := range reloader.clients {
+		select {
+		case client <- message:
+		default:
+			// the browser has not received the last reload yet, so replace it with a
+			// full reload that will pick up both sets of changes
+			select {
+			case <-client:
+			default:
+			}
+			client <- fullReload
+		}
+	}
+}
+
+// onlyStylesheets will return true if every file is a stylesheet that can be
+// swapped without reloading the page.
+func onlyStylesheets(files []string) bool {
+	for _, file := range files {
+		switch path.Ext(strings.TrimSuffix(file, ".liquid")) {
+		case ".css", ".scss":
+		default:
+			return false
+		}
+	}
+	return true
+}
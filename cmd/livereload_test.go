@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Shopify/themekit/src/file"
+)
+
+func TestLiveReload(t *testing.T) {
+	reloader := newLiveReload(50 * time.Millisecond)
+	server := httptest.NewServer(reloader.handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/livereload.js")
+	if assert.Nil(t, err) {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "application/javascript", resp.Header.Get("Content-Type"))
+		assert.Contains(t, string(body), "EventSource")
+	}
+
+	resp, err = http.Get(server.URL + "/events")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+
+	reader := bufio.NewReader(resp.Body)
+	nextEvent := func() string {
+		lines := []string{}
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil || line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+	assert.Equal(t, ": connected\n", nextEvent())
+
+	ctx, _, _, _, _ := createTestCtx()
+	reloader.notify(ctx, file.Event{Path: "assets/theme.scss.liquid"})
+	reloader.notify(ctx, file.Event{Path: "assets/app.css"})
+	assert.Equal(t, "event: reload\ndata: {\"css\":true,\"files\":[\"assets/theme.scss.liquid\",\"assets/app.css\"]}\n", nextEvent())
+
+	reloader.notify(ctx, file.Event{Path: "assets/app.css"})
+	reloader.notify(ctx, file.Event{Path: "sections/header.liquid"})
+	assert.Equal(t, "event: reload\ndata: {\"css\":false,\"files\":[\"assets/app.css\",\"sections/header.liquid\"]}\n", nextEvent())
+}
+
+func TestLiveReloadReload(t *testing.T) {
+	ctx, _, _, _, _ := createTestCtx()
+	reloader := newLiveReload(time.Hour)
+	client := make(chan []byte, 1)
+	reloader.clients[client] = true
+
+	reloader.flush()
+	assert.Equal(t, 0, len(client))
+
+	reloader.reload(ctx, []file.Event{{Path: "assets/app.css"}})
+	reloader.reload(ctx, []file.Event{{Path: "assets/theme.css"}})
+	assert.Equal(t, `{"css":false,"files":["assets/theme.css"]}`, string(<-client))
+}
+
+func TestOnlyStylesheets(t *testing.T) {
+	assert.True(t, onlyStylesheets([]string{"assets/app.css", "assets/theme.scss.liquid", "assets/theme.css.liquid"}))
+	assert.False(t, onlyStylesheets([]string{"assets/app.css", "assets/app.js"}))
+	assert.False(t, onlyStylesheets([]string{"sections/header.liquid"}))
+}
+
+func TestStartLiveReload(t *testing.T) {
+	reloader, err := startLiveReload("127.0.0.1:0")
+	if assert.Nil(t, err) {
+		assert.Nil(t, reloader.Close())
+	}
+
+	_, err = startLiveReload("not an address")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not start live reload server")
+	}
+}
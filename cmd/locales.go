@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/file"
+	"github.com/Shopify/themekit/src/locales"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+var localesCmd = &cobra.Command{
+	Use:   "locales",
+	Short: "Check and translate the locale files of your theme",
+	Long: `Locales has tools for keeping the files in your locales directory in sync
+ with your default locale. Use check to find problems and export and import to
+ let translators work in a spreadsheet.
+ `,
+}
+
+var localesCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Report missing, extra and empty translations",
+	Long: `Check will compare every locale file against the default locale and report
+ keys that are missing, keys that do not exist in the default locale, empty
+ translations and translations that do not use the same {{ variables }}.
+ Schema locales are compared with the default schema locale.
+ `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmdutil.ForLocal(flags, args, checkLocales)
+	},
+}
+
+var localesExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export all translations to a single file",
+	Long: `Export will write all of the translations into a csv with one row per key
+ and one column per locale, the default locale being the first column. The csv
+ is printed unless the --output flag is provided.
+ `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmdutil.ForLocal(flags, args, exportLocales)
+	},
+}
+
+var localesImportCmd = &cobra.Command{
+	Use:   "import <filename>",
+	Short: "Import translations from a csv",
+	Long: `Import will read a csv in the format created by export and rebuild the
+ locale files with its translations. Keys are written in a stable sorted order,
+ blank translations are removed and keys that are not in the csv are kept.
+ `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmdutil.ForLocal(flags, args, importLocales)
+	},
+}
+
+func checkLocales(ctx *cmdutil.Ctx) error {
+	group, err := loadLocales(ctx, false)
+	if err != nil {
+		return err
+	}
+	schemaGroup, err := loadLocales(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	for _, set := range [][]locales.Locale{group, schemaGroup} {
+		if len(set) == 0 {
+			continue
+		}
+		def, err := locales.FindDefault(set, set[0].Schema)
+		if err != nil {
+			return fmt.Errorf("[%s] %s", colors.Green(ctx.Env.Name), err)
+		}
+		for _, locale := range set {
+			if locale.Key == def.Key {
+				continue
+			}
+			problems := locales.Compare(def, locale)
+			if len(problems) == 0 {
+				ctx.Log.Printf("[%s] %s is complete", colors.Green(ctx.Env.Name), colors.Blue(locale.Key))
+			}
+			for _, problem := range problems {
+				ctx.Err("[%s] %s %s %s %s", colors.Green(ctx.Env.Name), colors.Blue(locale.Key), colors.Yellow(problem.Kind), problem.Key, problem.Detail)
+			}
+		}
+	}
+
+	if len(group) == 0 && len(schemaGroup) == 0 {
+		return fmt.Errorf("[%s] no locale files found", colors.Green(ctx.Env.Name))
+	}
+	return nil
+}
+
+func exportLocales(ctx *cmdutil.Ctx) error {
+	if !ctx.Flags.CSV {
+		return fmt.Errorf("please specify an export format, currently only --csv is supported")
+	}
+
+	set, err := loadLocales(ctx, ctx.Flags.Schema)
+	if err != nil {
+		return err
+	}
+	def, err := locales.FindDefault(set, ctx.Flags.Schema)
+	if err != nil {
+		return fmt.Errorf("[%s] %s", colors.Green(ctx.Env.Name), err)
+	}
+
+	others := []locales.Locale{}
+	for _, locale := range set {
+		if locale.Key != def.Key {
+			others = append(others, locale)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := locales.WriteCSV(&buf, def, others); err != nil {
+		return err
+	}
+
+	if ctx.Flags.Output == "" {
+		ctx.Log.Print(buf.String())
+		return nil
+	}
+	if err := ioutil.WriteFile(ctx.Flags.Output, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	ctx.Log.Printf("[%s] exported %d locales to %s", colors.Green(ctx.Env.Name), len(set), colors.Blue(ctx.Flags.Output))
+	return nil
+}
+
+func importLocales(ctx *cmdutil.Ctx) error {
+	if ctx.Env.ReadOnly {
+		return fmt.Errorf("[%s] environment is readonly", colors.Green(ctx.Env.Name))
+	} else if len(ctx.Args) != 1 {
+		return fmt.Errorf("please specify a single csv file to import")
+	}
+
+	csvFile, err := os.Open(ctx.Args[0])
+	if err != nil {
+		return err
+	}
+	defer csvFile.Close()
+
+	translations, err := locales.ReadCSV(csvFile)
+	if err != nil {
+		return err
+	}
+
+	for name, values := range translations {
+		key := filepath.ToSlash(filepath.Join("locales", name+".json"))
+		locale := locales.New(key)
+		if asset, err := shopify.ReadAsset(ctx.Env, key); err == nil {
+			if locale, err = locales.Parse(key, asset.Value); err != nil {
+				ctx.Err("[%s] %s", colors.Green(ctx.Env.Name), err)
+				continue
+			}
+		}
+
+		locale.Import(values)
+		data, err := locale.Marshal()
+		if err != nil {
+			ctx.Err("[%s] could not build %s: %s", colors.Green(ctx.Env.Name), colors.Blue(key), err)
+			continue
+		}
+
+		if err := (shopify.Asset{Key: key, Value: string(data)}).Write(ctx.Env.Directory); err != nil {
+			ctx.Err("[%s] error writing %s: %s", colors.Green(ctx.Env.Name), colors.Blue(key), err)
+			continue
+		}
+		ctx.Log.Printf("[%s] Successfully wrote %s to disk", colors.Green(ctx.Env.Name), colors.Blue(key))
+		ctx.DoneTask(file.Update)
+	}
+
+	return nil
+}
+
+// loadLocales will read and parse all of the storefront or schema locale files
+// in the project.
+func loadLocales(ctx *cmdutil.Ctx, schema bool) ([]locales.Locale, error) {
+	assets, err := shopify.FindAssets(ctx.Env, "locales")
+	if err != nil {
+		return nil, err
+	}
+
+	set := []locales.Locale{}
+	for _, asset := range assets {
+		if filepath.Ext(asset.Key) != ".json" {
+			continue
+		}
+		locale, err := locales.Parse(asset.Key, asset.Value)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %s", colors.Green(ctx.Env.Name), err)
+		} else if locale.Schema == schema {
+			set = append(set, locale)
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckLocales(t *testing.T) {
+	ctx, _, _, stdOut, stdErr := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "localesdir")
+	assert.Nil(t, checkLocales(ctx))
+	assert.Contains(t, stdErr.String(), "locales/fr.json interpolation general.title expected [shop_name] but found []")
+	assert.Contains(t, stdOut.String(), "locales/fr.schema.json is complete")
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	err := checkLocales(ctx)
+	assert.NotNil(t, err)
+
+	dir, _ := ioutil.TempDir("", "locales")
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "locales"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "locales", "fr.json"), []byte(`{"a": "b"}`), 0644)
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Env.Directory = dir
+	err = checkLocales(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "no default locale found")
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "locales", "fr.json"), []byte(`{"a": `), 0644)
+	err = checkLocales(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not parse locales/fr.json")
+	}
+}
+
+func TestExportLocales(t *testing.T) {
+	ctx, _, _, _, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "localesdir")
+	err := exportLocales(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "only --csv is supported")
+	}
+
+	ctx, _, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "localesdir")
+	ctx.Flags.CSV = true
+	assert.Nil(t, exportLocales(ctx))
+	assert.Equal(t, "key,en.default,fr\ngeneral.footer,Thanks,Merci\ngeneral.title,Welcome to {{ shop_name }},Bienvenue\n", stdOut.String())
+
+	ctx, _, _, stdOut, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "localesdir")
+	ctx.Flags.CSV = true
+	ctx.Flags.Schema = true
+	ctx.Flags.Output = filepath.Join("_testdata", "locales.csv")
+	defer os.Remove(ctx.Flags.Output)
+	assert.Nil(t, exportLocales(ctx))
+	assert.Contains(t, stdOut.String(), "exported 2 locales")
+	data, _ := ioutil.ReadFile(ctx.Flags.Output)
+	assert.Equal(t, "key,en.default.schema,fr.schema\nsettings.color,Color,Couleur\n", string(data))
+}
+
+func TestImportLocales(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "locales")
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "locales"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "locales", "fr.json"), []byte(`{"z": "garde", "a": "vieux"}`), 0644)
+	csvPath := filepath.Join(dir, "import.csv")
+	ioutil.WriteFile(csvPath, []byte("key,fr,de\na,nouveau,neu\nb.c,<b>gras</b>,\n"), 0644)
+
+	ctx, _, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = dir
+	ctx.Args = []string{csvPath}
+	assert.Nil(t, importLocales(ctx))
+	assert.Contains(t, stdOut.String(), "Successfully wrote locales/fr.json")
+
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "locales", "fr.json"))
+	assert.Equal(t, "{\n  \"a\": \"nouveau\",\n  \"b\": {\n    \"c\": \"<b>gras</b>\"\n  },\n  \"z\": \"garde\"\n}\n", string(data))
+	data, _ = ioutil.ReadFile(filepath.Join(dir, "locales", "de.json"))
+	assert.Equal(t, "{\n  \"a\": \"neu\"\n}\n", string(data))
+
+	ctx, _, _, _, _ = createTestCtx()
+	err := importLocales(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "single csv file")
+	}
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Env.ReadOnly = true
+	ctx.Args = []string{csvPath}
+	err = importLocales(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "environment is readonly")
+	}
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Args = []string{filepath.Join(dir, "nope.csv")}
+	assert.NotNil(t, importLocales(ctx))
+}
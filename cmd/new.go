@@ -2,12 +2,20 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	_ "github.com/Shopify/themekit/cmd/static" // This will import the asset bundle
+	"github.com/Shopify/themekit/src/archive"
 	"github.com/Shopify/themekit/src/cmdutil"
 	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/file"
 	"github.com/Shopify/themekit/src/shopify"
 	"github.com/Shopify/themekit/src/static"
 )
@@ -21,17 +29,59 @@ var newCmd = &cobra.Command{
 	the same directory it's called from. Use the --dir flag to specify a custom directory where the generated files
 	should be placed.
 
+	Use the --from flag to start from an existing theme instead. When --from is a
+	publicly reachable url to a zip file, shopify will import the zip itself which is
+	much faster than uploading the files one by one. Theme kit will wait for the
+	import to finish and then download the files. Use the --role flag to set the role
+	of the imported theme. When --from is a path to a zip, tar.gz or directory on
+	your machine then its files are used instead of the minimal template.
+
+	Use the --template flag to scaffold the theme from a starter. It can be the name
+	of a starter built into theme kit, a directory, a zip or tar.gz file or a git
+	url. Variables in the starter files like {{% .Name %}}, {{% .Store %}},
+	{{% .Domain %}} and {{% .ThemeID %}} are replaced with the theme name, the
+	store name, the store domain and the id of the new theme.
+
   For more information, refer to https://shopify.dev/tools/theme-kit/command-reference#new.
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// This is a hack to get around theme ID validation for the list operation which doesn't need it
 		flags.ThemeID = "1337"
 		return cmdutil.ForDefaultClient(flags, args, func(ctx *cmdutil.Ctx) error {
-			return newTheme(ctx, static.Unbundle)
+			if ctx.Flags.From != "" && ctx.Flags.Template != "" {
+				return fmt.Errorf("--from and --template cannot be used together")
+			} else if isURL(ctx.Flags.From) {
+				return importTheme(ctx)
+			}
+			generate, cleanup, err := themeGenerator(ctx)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			return newTheme(ctx, generate)
 		})
 	},
 }
 
+// gitClone will make a shallow clone of a git repository into dir. The url is
+// passed after -- so that it is never read as an option by git.
+var gitClone = func(url, dir string) error {
+	out, err := exec.Command("git", "clone", "--depth", "1", "--", url, dir).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+var (
+	// themeProcessingInterval is how long to wait between checks if shopify has
+	// finished importing a theme.
+	themeProcessingInterval = 5 * time.Second
+	// themeProcessingTimeout is how long to wait for shopify to import a theme
+	// before giving up.
+	themeProcessingTimeout = 10 * time.Minute
+)
+
 func newTheme(ctx *cmdutil.Ctx, generate func(ctx *cmdutil.Ctx) error) error {
 	theme, err := ctx.Client.CreateNewTheme(ctx.Flags.Name)
 	if err != nil {
@@ -55,3 +105,209 @@ func newTheme(ctx *cmdutil.Ctx, generate func(ctx *cmdutil.Ctx) error) error {
 	ctx.Log.Printf("[%s] uploading new files to shopify", colors.Yellow(ctx.Env.Domain))
 	return deploy(ctx)
 }
+
+// importTheme will create a new theme from a zip file at a url. Shopify imports the
+// zip in the background so this waits for it to finish before the files are
+// downloaded.
+func importTheme(ctx *cmdutil.Ctx) error {
+	theme, err := ctx.Client.CreateThemeFromSrc(ctx.Flags.Name, ctx.Flags.From, ctx.Flags.Role)
+	if err != nil {
+		if err == shopify.ErrThemeNameRequired {
+			return fmt.Errorf("a theme name is required, please use the --name flag to define it")
+		}
+		return err
+	}
+	ctx.Log.Printf("[%s] theme created, waiting for shopify to import %s", colors.Yellow(ctx.Env.Domain), colors.Blue(ctx.Flags.From))
+
+	if err := waitForProcessing(ctx, theme); err != nil {
+		return err
+	}
+
+	ctx.Env.ThemeID = fmt.Sprintf("%v", theme.ID)
+	if err := createConfig(ctx); err != nil {
+		return err
+	}
+	ctx.Log.Printf("[%s] config created", colors.Yellow(ctx.Env.Domain))
+
+	ctx.Log.Printf("[%s] downloading theme files", colors.Yellow(ctx.Env.Domain))
+	return download(ctx)
+}
+
+func waitForProcessing(ctx *cmdutil.Ctx, theme shopify.Theme) error {
+	timeout := time.After(themeProcessingTimeout)
+	for theme.Processing {
+		select {
+		case <-timeout:
+			return fmt.Errorf("[%s] timed out waiting for shopify to import theme %v", colors.Yellow(ctx.Env.Domain), theme.ID)
+		case <-time.After(themeProcessingInterval):
+		}
+
+		var err error
+		if theme, err = ctx.Client.GetInfo(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// themeGenerator will return the function that generates the files of a new theme
+// based on the --from and --template flags. Templates are resolved before the
+// theme is created so that a bad template does not leave an empty theme behind.
+// The cleanup function removes any temporary files once the theme is generated.
+func themeGenerator(ctx *cmdutil.Ctx) (func(*cmdutil.Ctx) error, func(), error) {
+	cleanup := func() {}
+	name := ctx.Flags.Template
+	switch {
+	case ctx.Flags.From != "":
+		return func(ctx *cmdutil.Ctx) error { return unpackTheme(ctx, ctx.Flags.From, false) }, cleanup, nil
+	case name == "":
+		return static.Unbundle, cleanup, nil
+	case static.HasStarter(name):
+		return func(ctx *cmdutil.Ctx) error { return static.UnbundleStarter(ctx, name) }, cleanup, nil
+	case isGitURL(name):
+		dir, err := ioutil.TempDir("", "themekit-template")
+		if err != nil {
+			return nil, cleanup, err
+		}
+		if err := gitClone(name, dir); err != nil {
+			os.RemoveAll(dir)
+			return nil, cleanup, fmt.Errorf("could not clone %s: %s", name, err)
+		}
+		return func(ctx *cmdutil.Ctx) error { return unpackTheme(ctx, dir, true) }, func() { os.RemoveAll(dir) }, nil
+	}
+
+	if _, err := os.Stat(name); err != nil {
+		return nil, cleanup, fmt.Errorf(
+			"unknown template %s, use the name of a starter (%s), a directory, a zip or a git url",
+			name,
+			strings.Join(static.Starters(), ", "),
+		)
+	}
+	return func(ctx *cmdutil.Ctx) error { return unpackTheme(ctx, name, true) }, cleanup, nil
+}
+
+// unpackTheme will write the theme files from a zip, tar.gz or directory into the
+// project directory. A single folder that wraps the whole theme, like the one in
+// archives downloaded from github, is unwrapped. Files outside of the theme
+// folders and files with an ignored folder anywhere in their path are skipped. When the source is a template, variables in the
+// files are expanded and files that already exist are not overwritten.
+func unpackTheme(ctx *cmdutil.Ctx, src string, template bool) error {
+	filter, err := file.NewFilter(ctx.Env.Directory, ctx.Env.IgnoredFiles, ctx.Env.Ignores)
+	if err != nil {
+		return err
+	}
+
+	var walk func(fn func(name string, data []byte) error) error
+	if archive.Format(src) != "" {
+		walk = func(fn func(name string, data []byte) error) error { return archive.Walk(src, fn) }
+	} else {
+		info, err := os.Stat(src)
+		if err != nil {
+			return err
+		} else if !info.IsDir() {
+			return fmt.Errorf("%s is not a url, zip, tar.gz or directory", src)
+		}
+
+		srcDir, _ := filepath.Abs(src)
+		projectDir, _ := filepath.Abs(ctx.Env.Directory)
+		if srcDir == projectDir {
+			return nil
+		}
+		walk = func(fn func(name string, data []byte) error) error { return walkDir(src, fn) }
+	}
+
+	names := []string{}
+	if err := walk(func(name string, data []byte) error {
+		names = append(names, name)
+		return nil
+	}); err != nil {
+		return err
+	}
+	root := themeRoot(filter, names)
+
+	return walk(func(name string, data []byte) error {
+		key := themeKey(filter, root, name)
+		if key == "" {
+			return nil
+		}
+		path := filepath.Join(ctx.Env.Directory, filepath.FromSlash(key))
+		if template {
+			if _, err := os.Stat(path); err == nil {
+				ctx.Log.Printf("\t%s %s.\n", colors.Blue("Exists"), path)
+				return nil
+			}
+			if data, err = static.Expand(ctx, key, data); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, data, 0644)
+	})
+}
+
+// walkDir will call fn with the slash separated path and contents of every file
+// in dir.
+func walkDir(dir string, fn func(name string, data []byte) error) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(name), data)
+	})
+}
+
+// themeRoot will return the folder that wraps the whole theme, like the one in
+// archives downloaded from github, or an empty string if the theme folders are
+// at the top level. Only a single folder that is common to every file is
+// considered a wrapper.
+func themeRoot(filter file.Filter, names []string) string {
+	root := ""
+	for _, name := range names {
+		if !filter.Match(name) {
+			return ""
+		}
+		parts := strings.SplitN(name, "/", 2)
+		if len(parts) < 2 || (root != "" && parts[0] != root) {
+			return ""
+		}
+		root = parts[0]
+	}
+	if root == "" {
+		return ""
+	}
+	return root + "/"
+}
+
+// themeKey will return the key of a theme file once the root folder is removed.
+// An empty string is returned if the file is not part of a theme or any part of
+// its path is ignored.
+func themeKey(filter file.Filter, root, name string) string {
+	if !strings.HasPrefix(name, root) {
+		return ""
+	}
+	if key := strings.TrimPrefix(name, root); !filter.Match(key) {
+		return key
+	}
+	return ""
+}
+
+func isURL(src string) bool {
+	return strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+}
+
+func isGitURL(src string) bool {
+	return strings.HasSuffix(src, ".git") ||
+		strings.HasPrefix(src, "git@") ||
+		strings.HasPrefix(src, "git://") ||
+		strings.HasPrefix(src, "ssh://")
+}
@@ -3,12 +3,19 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/Shopify/themekit/src/archive"
 	"github.com/Shopify/themekit/src/cmdutil"
 	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/file"
 	"github.com/Shopify/themekit/src/shopify"
 )
 
@@ -51,3 +58,226 @@ func TestNewTheme(t *testing.T) {
 		assert.Contains(t, err.Error(), "oh no")
 	}
 }
+
+func TestImportTheme(t *testing.T) {
+	defer func(interval time.Duration) { themeProcessingInterval = interval }(themeProcessingInterval)
+	themeProcessingInterval = time.Millisecond
+	src := "https://example.com/theme.zip"
+
+	ctx, client, conf, stdOut, _ := createTestCtx()
+	ctx.Flags.Name = "name"
+	ctx.Flags.From = src
+	ctx.Flags.Role = "main"
+	client.On("CreateThemeFromSrc", "name", src, "main").Return(shopify.Theme{ID: 42, Processing: true}, nil)
+	client.On("GetInfo").Return(shopify.Theme{ID: 42, Processing: true}, nil).Once()
+	client.On("GetInfo").Return(shopify.Theme{ID: 42}, nil).Once()
+	conf.On("Set", "development", env.Env{ThemeID: "42"}).Return(nil, nil)
+	conf.On("Save").Return(nil)
+	client.On("GetAllAssets").Return([]shopify.Asset{}, nil)
+	err := importTheme(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "No files to download")
+	}
+	assert.Contains(t, stdOut.String(), "waiting for shopify to import "+src)
+	client.AssertNumberOfCalls(t, "GetInfo", 2)
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Flags.From = src
+	client.On("CreateThemeFromSrc", "", src, "").Return(shopify.Theme{}, shopify.ErrThemeNameRequired)
+	err = importTheme(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "please use the --name flag")
+	}
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Flags.Name = "name"
+	ctx.Flags.From = src
+	client.On("CreateThemeFromSrc", "name", src, "").Return(shopify.Theme{ID: 42, Processing: true}, nil)
+	client.On("GetInfo").Return(shopify.Theme{}, fmt.Errorf("server error"))
+	err = importTheme(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "server error")
+	}
+}
+
+func TestWaitForProcessing(t *testing.T) {
+	defer func(interval, timeout time.Duration) {
+		themeProcessingInterval, themeProcessingTimeout = interval, timeout
+	}(themeProcessingInterval, themeProcessingTimeout)
+	themeProcessingInterval = time.Millisecond
+	themeProcessingTimeout = 20 * time.Millisecond
+
+	ctx, client, _, _, _ := createTestCtx()
+	assert.Nil(t, waitForProcessing(ctx, shopify.Theme{ID: 42}))
+	client.AssertNotCalled(t, "GetInfo")
+
+	ctx, client, _, _, _ = createTestCtx()
+	client.On("GetInfo").Return(shopify.Theme{ID: 42, Processing: true}, nil)
+	err := waitForProcessing(ctx, shopify.Theme{ID: 42, Processing: true})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "timed out waiting for shopify to import theme 42")
+	}
+}
+
+func TestUnpackTheme(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "unpack")
+	defer os.RemoveAll(dir)
+
+	zipPath := filepath.Join(dir, "dawn-main.zip")
+	writer, _ := archive.Create(zipPath)
+	writer.Add("dawn-main/layout/theme.liquid", []byte("{{ content_for_layout }}"))
+	writer.Add("dawn-main/templates/customers/login.liquid", []byte("login"))
+	writer.Add("dawn-main/README.md", []byte("readme"))
+	writer.Add("dawn-main/node_modules/pkg/templates/customers/login.liquid", []byte("vendored"))
+	writer.Close()
+
+	ctx, _, _, _, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join(dir, "fromzip")
+	assert.Nil(t, unpackTheme(ctx, zipPath, false))
+	data, err := ioutil.ReadFile(filepath.Join(ctx.Env.Directory, "layout", "theme.liquid"))
+	assert.Nil(t, err)
+	assert.Equal(t, "{{ content_for_layout }}", string(data))
+	data, err = ioutil.ReadFile(filepath.Join(ctx.Env.Directory, "templates", "customers", "login.liquid"))
+	assert.Nil(t, err)
+	assert.Equal(t, "login", string(data))
+	_, err = os.Stat(filepath.Join(ctx.Env.Directory, "node_modules"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(ctx.Env.Directory, "README.md"))
+	assert.True(t, os.IsNotExist(err))
+
+	src := ctx.Env.Directory
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join(dir, "fromdir")
+	assert.Nil(t, unpackTheme(ctx, src, false))
+	_, err = os.Stat(filepath.Join(ctx.Env.Directory, "layout", "theme.liquid"))
+	assert.Nil(t, err)
+
+	assert.Nil(t, unpackTheme(ctx, ctx.Env.Directory, false))
+	assert.NotNil(t, unpackTheme(ctx, filepath.Join(dir, "nope"), false))
+	err = unpackTheme(ctx, filepath.Join(ctx.Env.Directory, "layout", "theme.liquid"), false)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "is not a url, zip, tar.gz or directory")
+	}
+}
+
+func TestThemeGenerator(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "generator")
+	defer os.RemoveAll(dir)
+
+	template := filepath.Join(dir, "template")
+	os.MkdirAll(filepath.Join(template, "layout"), 0755)
+	ioutil.WriteFile(filepath.Join(template, "layout", "theme.liquid"), []byte("{{% .Name %}} {{% .ThemeID %}} {{ content_for_layout }}"), 0644)
+
+	ctx, _, _, _, _ := createTestCtx()
+	_, cleanup, err := themeGenerator(ctx)
+	assert.Nil(t, err)
+	cleanup()
+
+	ctx.Flags.Template = "default"
+	_, _, err = themeGenerator(ctx)
+	assert.Nil(t, err)
+
+	ctx, _, _, stdOut, _ := createTestCtx()
+	ctx.Flags.Name = "Agency"
+	ctx.Flags.Template = template
+	ctx.Env.ThemeID = "42"
+	ctx.Env.Directory = filepath.Join(dir, "fromdir")
+	generate, _, err := themeGenerator(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, generate(ctx))
+	data, _ := ioutil.ReadFile(filepath.Join(ctx.Env.Directory, "layout", "theme.liquid"))
+	assert.Equal(t, "Agency 42 {{ content_for_layout }}", string(data))
+	assert.Nil(t, generate(ctx))
+	assert.Contains(t, stdOut.String(), "Exists")
+
+	defer func(clone func(string, string) error) { gitClone = clone }(gitClone)
+	cloned := ""
+	gitClone = func(url, dst string) error {
+		cloned = url
+		os.MkdirAll(filepath.Join(dst, "snippets"), 0755)
+		return ioutil.WriteFile(filepath.Join(dst, "snippets", "header.liquid"), []byte("{{% .Name %}}"), 0644)
+	}
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Flags.Name = "Agency"
+	ctx.Flags.Template = "git@github.com:agency/base.git"
+	ctx.Env.Directory = filepath.Join(dir, "fromgit")
+	generate, cleanup, err = themeGenerator(ctx)
+	assert.Nil(t, err)
+	assert.Nil(t, generate(ctx))
+	cleanup()
+	assert.Equal(t, "git@github.com:agency/base.git", cloned)
+	data, _ = ioutil.ReadFile(filepath.Join(ctx.Env.Directory, "snippets", "header.liquid"))
+	assert.Equal(t, "Agency", string(data))
+
+	gitClone = func(url, dst string) error { return fmt.Errorf("repository not found") }
+	_, _, err = themeGenerator(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not clone git@github.com:agency/base.git: repository not found")
+	}
+
+	ctx.Flags.Template = "nope"
+	_, _, err = themeGenerator(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "unknown template nope, use the name of a starter (default")
+	}
+
+	ctx.Flags.Template = ""
+	ctx.Flags.From = template
+	generate, _, err = themeGenerator(ctx)
+	assert.Nil(t, err)
+	ctx.Env.Directory = filepath.Join(dir, "fromsrc")
+	assert.Nil(t, generate(ctx))
+	data, _ = ioutil.ReadFile(filepath.Join(ctx.Env.Directory, "layout", "theme.liquid"))
+	assert.Equal(t, "{{% .Name %}} {{% .ThemeID %}} {{ content_for_layout }}", string(data))
+}
+
+func TestIsGitURL(t *testing.T) {
+	assert.True(t, isGitURL("https://github.com/agency/base.git"))
+	assert.True(t, isGitURL("git@github.com:agency/base.git"))
+	assert.True(t, isGitURL("ssh://git@github.com/agency/base"))
+	assert.False(t, isGitURL("https://github.com/agency/base/archive/main.zip"))
+	assert.False(t, isGitURL("default"))
+}
+
+func TestThemeRoot(t *testing.T) {
+	filter, _ := file.NewFilter("", []string{}, []string{})
+	assert.Equal(t, "dawn-main/", themeRoot(filter, []string{"dawn-main/layout/theme.liquid", "dawn-main/README.md"}))
+	assert.Equal(t, "", themeRoot(filter, []string{"layout/theme.liquid", "README.md"}))
+	assert.Equal(t, "", themeRoot(filter, []string{"snippets/header.liquid"}))
+	assert.Equal(t, "", themeRoot(filter, []string{"a/layout/theme.liquid", "b/layout/theme.liquid"}))
+	assert.Equal(t, "", themeRoot(filter, []string{"README.md"}))
+	assert.Equal(t, "", themeRoot(filter, []string{}))
+}
+
+func TestThemeKey(t *testing.T) {
+	filter, _ := file.NewFilter("", []string{}, []string{})
+	assert.Equal(t, "layout/theme.liquid", themeKey(filter, "", "layout/theme.liquid"))
+	assert.Equal(t, "layout/theme.liquid", themeKey(filter, "dawn-main/", "dawn-main/layout/theme.liquid"))
+	assert.Equal(t, "templates/customers/login.liquid", themeKey(filter, "theme/", "theme/templates/customers/login.liquid"))
+	assert.Equal(t, "", themeKey(filter, "dawn-main/", "dawn-main/README.md"))
+	assert.Equal(t, "", themeKey(filter, "dawn-main/", "dawn-main/.git/config"))
+	assert.Equal(t, "", themeKey(filter, "dawn-main/", "other/layout/theme.liquid"))
+	assert.Equal(t, "", themeKey(filter, "", "node_modules/pkg/templates/x.liquid"))
+	assert.Equal(t, "", themeKey(filter, "", "vendor/foo/assets/a.js"))
+	assert.Equal(t, "", themeKey(filter, "dawn-main/", "dawn-main/node_modules/pkg/templates/x.liquid"))
+}
+
+func TestIsURL(t *testing.T) {
+	assert.True(t, isURL("https://example.com/theme.zip"))
+	assert.True(t, isURL("http://example.com/theme.zip"))
+	assert.False(t, isURL("theme.zip"))
+	assert.False(t, isURL(""))
+}
+
+func TestGitClone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, _ := ioutil.TempDir("", "clone")
+	defer os.RemoveAll(dir)
+	marker := filepath.Join(dir, "marker")
+	assert.NotNil(t, gitClone("--upload-pack=touch "+marker, filepath.Join(dir, "theme")))
+	_, err := os.Stat(marker)
+	assert.True(t, os.IsNotExist(err), "the url is not read as an option")
+}
@@ -2,55 +2,253 @@ package cmd
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Shopify/themekit/src/cmdutil"
 	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/file"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+const (
+	// execNotifyPrefix marks a notify target as a command to run
+	execNotifyPrefix = "exec:"
+	// signatureHeader is the header that holds the HMAC of the body of a url
+	// notification when a notify secret is set
+	signatureHeader = "X-Themekit-Signature"
+)
+
+var (
+	// notifyDelay is how long notifications wait for more files to change before
+	// they are sent, so that a batch of uploads is sent as one notification.
+	notifyDelay = 300 * time.Millisecond
+	// notifyRetries is how many times a url notification is tried before giving up
+	notifyRetries = 3
+	// notifyRetryDelay is how long to wait between tries, it grows with every try
+	notifyRetryDelay = time.Second
 )
 
 type notifyAdapter interface {
-	notify(*cmdutil.Ctx, string)
+	notify(*cmdutil.Ctx, file.Event)
+}
+
+// notification is the payload sent to url and exec notify targets
+type notification struct {
+	Env     string         `json:"env"`
+	ThemeID string         `json:"theme_id"`
+	Shop    string         `json:"shop"`
+	Files   []string       `json:"files"`
+	Changes []notifyChange `json:"changes"`
+}
+
+type notifyChange struct {
+	Path     string `json:"path"`
+	Op       string `json:"op"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// newNotifyAdapters will create an adapter that notifies every notify target of
+// the environment.
+func newNotifyAdapters(e *env.Env) notifyAdapter {
+	adapters := multiNotify{}
+	for _, target := range append([]string{e.Notify}, e.NotifyTargets...) {
+		if target = strings.TrimSpace(target); target != "" {
+			adapters = append(adapters, newNotifyAdapter(target, e.NotifySecret))
+		}
+	}
+	if len(adapters) == 0 {
+		return &noopNotify{}
+	} else if len(adapters) == 1 {
+		return adapters[0]
+	}
+	return adapters
 }
 
-func newNotifyAdapter(notifyPath string) notifyAdapter {
+func newNotifyAdapter(notifyPath, secret string) notifyAdapter {
 	if notifyPath == "" {
 		return &noopNotify{}
+	} else if strings.HasPrefix(notifyPath, execNotifyPrefix) {
+		execNote := &execNotify{command: strings.TrimSpace(strings.TrimPrefix(notifyPath, execNotifyPrefix))}
+		execNote.notifyBatch = newNotifyBatch(notifyDelay, execNote.send)
+		return execNote
 	} else if u, err := url.Parse(notifyPath); err == nil && u.Scheme != "" && u.Host != "" {
-		return &urlNotify{
-			url: notifyPath,
+		urlNote := &urlNotify{
+			url:    notifyPath,
+			secret: secret,
 			client: http.Client{
 				Timeout: time.Second,
 			},
 		}
+		urlNote.notifyBatch = newNotifyBatch(notifyDelay, urlNote.send)
+		return urlNote
 	}
 	return &fileNotify{path: notifyPath}
 }
 
 type noopNotify struct{}
 
-func (noop *noopNotify) notify(*cmdutil.Ctx, string) {}
+func (noop *noopNotify) notify(*cmdutil.Ctx, file.Event) {}
+
+// multiNotify will notify every adapter in it
+type multiNotify []notifyAdapter
+
+func (adapters multiNotify) notify(ctx *cmdutil.Ctx, event file.Event) {
+	for _, adapter := range adapters {
+		adapter.notify(ctx, event)
+	}
+}
+
+// notifyBatch collects events until no more events have happened for the delay
+// and then sends them all at once.
+type notifyBatch struct {
+	delay   time.Duration
+	send    func(*cmdutil.Ctx, []file.Event)
+	mu      sync.Mutex
+	ctx     *cmdutil.Ctx
+	pending []file.Event
+	timer   *time.Timer
+}
+
+func newNotifyBatch(delay time.Duration, send func(*cmdutil.Ctx, []file.Event)) *notifyBatch {
+	return &notifyBatch{delay: delay, send: send}
+}
+
+func (batch *notifyBatch) notify(ctx *cmdutil.Ctx, event file.Event) {
+	batch.mu.Lock()
+	defer batch.mu.Unlock()
+	batch.ctx = ctx
+	batch.pending = append(batch.pending, event)
+	if batch.timer == nil {
+		batch.timer = time.AfterFunc(batch.delay, batch.flush)
+	} else {
+		batch.timer.Reset(batch.delay)
+	}
+}
+
+// flush will send all of the pending events
+func (batch *notifyBatch) flush() {
+	batch.mu.Lock()
+	ctx, events := batch.ctx, batch.pending
+	batch.pending = nil
+	batch.timer = nil
+	batch.mu.Unlock()
+	if len(events) > 0 {
+		batch.send(ctx, events)
+	}
+}
+
+func newNotification(ctx *cmdutil.Ctx, events []file.Event) notification {
+	note := notification{
+		Env:     ctx.Env.Name,
+		ThemeID: ctx.Env.ThemeID,
+		Shop:    ctx.Env.Domain,
+		Files:   []string{},
+		Changes: []notifyChange{},
+	}
+	for _, event := range events {
+		change := notifyChange{Path: event.Path, Op: "update"}
+		if event.Op == file.Remove {
+			change.Op = "remove"
+		} else {
+			// the checksum is the one shopify reports for the file
+			asset, _ := shopify.ReadAsset(ctx.Env, event.Path)
+			change.Checksum = asset.Checksum
+		}
+		note.Files = append(note.Files, event.Path)
+		note.Changes = append(note.Changes, change)
+	}
+	return note
+}
 
 type urlNotify struct {
+	*notifyBatch
 	url    string
+	secret string
 	client http.Client
 }
 
-func (urlNote *urlNotify) notify(ctx *cmdutil.Ctx, path string) {
-	body, _ := json.Marshal(map[string]interface{}{"files": []string{path}})
-	resp, err := urlNote.client.Post(urlNote.url, "application/json", bytes.NewBuffer(body))
+func (urlNote *urlNotify) send(ctx *cmdutil.Ctx, events []file.Event) {
+	body, _ := json.Marshal(newNotification(ctx, events))
+
+	var err error
+	for attempt := 1; attempt <= notifyRetries; attempt++ {
+		if err = urlNote.post(body); err == nil {
+			return
+		} else if attempt < notifyRetries {
+			time.Sleep(time.Duration(attempt) * notifyRetryDelay)
+		}
+	}
+	ctx.Err(`[%s] Error while notifying webhook "%s": %s`, colors.Green(ctx.Env.Name), colors.Blue(urlNote.url), err)
+}
+
+func (urlNote *urlNotify) post(body []byte) error {
+	req, err := http.NewRequest("POST", urlNote.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if urlNote.secret != "" {
+		req.Header.Set(signatureHeader, signature(urlNote.secret, body))
+	}
+
+	resp, err := urlNote.client.Do(req)
 	if err != nil {
-		ctx.Log.Printf(
-			`[%s] Error while notifying webhook "%s": %s`,
-			colors.Green(ctx.Env.Name),
-			colors.Blue(ctx.Env.Notify),
-			err,
-		)
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// signature will return the HMAC-SHA256 of the body with the secret so that the
+// receiver of a notification can check that it came from this watch.
+func signature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// execNotify runs a local command with the notification as json on stdin
+type execNotify struct {
+	*notifyBatch
+	command string
+}
+
+func (execNote *execNotify) send(ctx *cmdutil.Ctx, events []file.Event) {
+	note := newNotification(ctx, events)
+	body, _ := json.Marshal(note)
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", execNote.command)
 	} else {
-		resp.Body.Close()
+		cmd = exec.Command("sh", "-c", execNote.command)
+	}
+	cmd.Dir = ctx.Env.Directory
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"THEMEKIT_ENV="+note.Env,
+		"THEMEKIT_THEME_ID="+note.ThemeID,
+		"THEMEKIT_STORE="+note.Shop,
+		"THEMEKIT_FILES="+strings.Join(note.Files, " "),
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		ctx.Err(`[%s] Error while running notify command "%s": %s %s`, colors.Green(ctx.Env.Name), colors.Blue(execNote.command), err, output)
 	}
 }
 
@@ -58,7 +256,7 @@ type fileNotify struct {
 	path string
 }
 
-func (fileNote *fileNotify) notify(*cmdutil.Ctx, string) {
+func (fileNote *fileNotify) notify(*cmdutil.Ctx, file.Event) {
 	os.Create(fileNote.path)
 	os.Chtimes(fileNote.path, time.Now(), time.Now())
 }
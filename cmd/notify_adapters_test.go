@@ -7,42 +7,73 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/file"
+	"github.com/Shopify/themekit/src/shopify"
 )
 
 func TestNewNotifyAdapter(t *testing.T) {
-	adapter := newNotifyAdapter("")
+	adapter := newNotifyAdapter("", "")
 	_, ok := adapter.(*noopNotify)
 	assert.True(t, ok)
 
-	adapter = newNotifyAdapter("note.txt")
+	adapter = newNotifyAdapter("note.txt", "")
 	_, ok = adapter.(*fileNotify)
 	assert.True(t, ok)
 
-	adapter = newNotifyAdapter("http://localhost:3000/notify")
+	adapter = newNotifyAdapter("http://localhost:3000/notify", "")
 	_, ok = adapter.(*urlNotify)
 	assert.True(t, ok)
+
+	adapter = newNotifyAdapter("exec: make reload", "")
+	execNote, ok := adapter.(*execNotify)
+	if assert.True(t, ok) {
+		assert.Equal(t, "make reload", execNote.command)
+	}
+}
+
+func TestNewNotifyAdapters(t *testing.T) {
+	_, ok := newNotifyAdapters(&env.Env{}).(*noopNotify)
+	assert.True(t, ok)
+
+	_, ok = newNotifyAdapters(&env.Env{Notify: "note.txt"}).(*fileNotify)
+	assert.True(t, ok)
+
+	adapters, ok := newNotifyAdapters(&env.Env{
+		Notify:        "note.txt",
+		NotifyTargets: []string{"http://localhost:3000/notify", " ", "exec:make reload"},
+		NotifySecret:  "secret",
+	}).(multiNotify)
+	if assert.True(t, ok) && assert.Equal(t, 3, len(adapters)) {
+		assert.Equal(t, "secret", adapters[1].(*urlNotify).secret)
+		_, ok = adapters[2].(*execNotify)
+		assert.True(t, ok)
+	}
 }
 
 func TestNoopAdapter(t *testing.T) {
-	adapter := newNotifyAdapter("")
+	adapter := newNotifyAdapter("", "")
 	ctx, _, _, _, _ := createTestCtx()
-	adapter.notify(ctx, "")
+	adapter.notify(ctx, file.Event{})
 }
 
 func TestNotifyFile(t *testing.T) {
 	notifyPath := filepath.Join("_testdata", "notify_file")
-	adapter := newNotifyAdapter(notifyPath)
+	adapter := newNotifyAdapter(notifyPath, "")
 	ctx, _, _, _, _ := createTestCtx()
 
 	os.Remove(notifyPath)
 	_, err := os.Stat(notifyPath)
 	assert.True(t, os.IsNotExist(err))
 
-	adapter.notify(ctx, "")
+	adapter.notify(ctx, file.Event{})
 	_, err = os.Stat(notifyPath)
 	assert.Nil(t, err)
 	// need to make the time different larger than milliseconds because windows
@@ -51,28 +82,138 @@ func TestNotifyFile(t *testing.T) {
 	info1, err := os.Stat(notifyPath)
 	assert.Nil(t, err)
 
-	adapter.notify(ctx, "")
+	adapter.notify(ctx, file.Event{})
 	info2, err := os.Stat(notifyPath)
 	assert.Nil(t, err)
 	assert.NotEqual(t, info1.ModTime(), info2.ModTime())
 }
 
 func TestNotifyURL(t *testing.T) {
+	requests := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
 		assert.Equal(t, r.Header.Get("Content-Type"), "application/json")
 		reqBody, err := ioutil.ReadAll(r.Body)
 		assert.Nil(t, err)
-		data := map[string]interface{}{}
+		assert.Equal(t, signature("secret", reqBody), r.Header.Get(signatureHeader))
+		data := notification{}
 		assert.Nil(t, json.Unmarshal(reqBody, &data))
-		assert.NotNil(t, data["files"])
-		files, _ := data["files"].([]interface{})
-		assert.Equal(t, 1, len(files))
-		assert.Equal(t, "assets/app.js", files[0])
+		assert.Equal(t, "development", data.Env)
+		assert.Equal(t, "123", data.ThemeID)
+		assert.Equal(t, "shop.myshopify.com", data.Shop)
+		assert.Equal(t, []string{"assets/app.js", "assets/old.js"}, data.Files)
+		assert.Equal(t, []notifyChange{
+			{Path: "assets/app.js", Op: "update", Checksum: "d41d8cd98f00b204e9800998ecf8427e"},
+			{Path: "assets/old.js", Op: "remove"},
+		}, data.Changes)
 	}))
 	defer server.Close()
 
+	ctx, _, _, _, stdErr := createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.ThemeID = "123"
+	ctx.Env.Domain = "shop.myshopify.com"
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+
+	adapter := newNotifyAdapter(server.URL, "secret").(*urlNotify)
+	adapter.notify(ctx, file.Event{Op: file.Update, Path: "assets/app.js"})
+	adapter.notify(ctx, file.Event{Op: file.Remove, Path: "assets/old.js"})
+	adapter.flush()
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, "", stdErr.String())
+}
+
+func TestNewNotification(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "notify")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "templates", "index.json"), []byte("{\n  \"a\": 1\n}\n"), 0644)
+
 	ctx, _, _, _, _ := createTestCtx()
+	ctx.Env.Directory = dir
+	note := newNotification(ctx, []file.Event{{Path: "templates/index.json", Op: file.Update}})
+	if assert.Equal(t, 1, len(note.Changes)) {
+		assert.Equal(t, shopify.NewAsset("templates/index.json", []byte(`{"a":1}`)).Checksum, note.Changes[0].Checksum)
+	}
+}
+
+func TestNotifyURLRetries(t *testing.T) {
+	defer func(delay time.Duration) { notifyRetryDelay = delay }(notifyRetryDelay)
+	notifyRetryDelay = time.Millisecond
 
-	adapter := newNotifyAdapter(server.URL)
-	adapter.notify(ctx, "assets/app.js")
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "", r.Header.Get(signatureHeader))
+		if requests < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	ctx, _, _, _, stdErr := createTestCtx()
+	adapter := newNotifyAdapter(server.URL, "").(*urlNotify)
+	adapter.send(ctx, []file.Event{{Op: file.Update, Path: "assets/app.js"}})
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, "", stdErr.String())
+
+	requests = -10
+	adapter.send(ctx, []file.Event{{Op: file.Update, Path: "assets/app.js"}})
+	assert.Equal(t, -7, requests)
+	assert.Contains(t, stdErr.String(), "Error while notifying webhook")
+	assert.Contains(t, stdErr.String(), "webhook responded with 502 Bad Gateway")
+}
+
+func TestNotifyExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exec notify tests use sh")
+	}
+	dir, _ := ioutil.TempDir("", "notify")
+	defer os.RemoveAll(dir)
+
+	ctx, _, _, _, stdErr := createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.Directory = dir
+	adapter := newNotifyAdapter("exec: cat > out.json && echo $THEMEKIT_ENV $THEMEKIT_FILES > out.txt", "").(*execNotify)
+	adapter.send(ctx, []file.Event{{Op: file.Remove, Path: "assets/app.js"}, {Op: file.Remove, Path: "assets/old.js"}})
+	assert.Equal(t, "", stdErr.String())
+
+	data := notification{}
+	body, _ := ioutil.ReadFile(filepath.Join(dir, "out.json"))
+	assert.Nil(t, json.Unmarshal(body, &data))
+	assert.Equal(t, []string{"assets/app.js", "assets/old.js"}, data.Files)
+	out, _ := ioutil.ReadFile(filepath.Join(dir, "out.txt"))
+	assert.Equal(t, "development assets/app.js assets/old.js\n", string(out))
+
+	adapter = newNotifyAdapter("exec: echo broken && exit 1", "").(*execNotify)
+	adapter.send(ctx, []file.Event{{Op: file.Remove, Path: "assets/app.js"}})
+	assert.Contains(t, stdErr.String(), `Error while running notify command "echo broken && exit 1": exit status 1 broken`)
+}
+
+func TestNotifyBatch(t *testing.T) {
+	ctx, _, _, _, _ := createTestCtx()
+	sent := make(chan []file.Event, 2)
+	batch := newNotifyBatch(50*time.Millisecond, func(_ *cmdutil.Ctx, events []file.Event) { sent <- events })
+
+	batch.flush()
+	batch.notify(ctx, file.Event{Path: "assets/app.js"})
+	batch.notify(ctx, file.Event{Path: "assets/app.css"})
+	select {
+	case events := <-sent:
+		assert.Equal(t, []file.Event{{Path: "assets/app.js"}, {Path: "assets/app.css"}}, events)
+	case <-time.After(time.Second):
+		t.Error("batch was never sent")
+	}
+	assert.Equal(t, 0, len(sent))
+}
+
+func TestMultiNotify(t *testing.T) {
+	ctx, _, _, _, _ := createTestCtx()
+	event := file.Event{Op: file.Update, Path: "assets/app.js"}
+	first, second := new(testAdapter), new(testAdapter)
+	first.On("notify", ctx, event)
+	second.On("notify", ctx, event)
+	multiNotify{first, second}.notify(ctx, event)
+	first.AssertExpectations(t)
+	second.AssertExpectations(t)
 }
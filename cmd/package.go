@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Shopify/themekit/src/archive"
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+// requiredThemeFiles are the files that shopify needs for a theme zip to be valid
+var requiredThemeFiles = []string{
+	"layout/theme.liquid",
+	"config/settings_schema.json",
+}
+
+var packageCmd = &cobra.Command{
+	Use:   "package",
+	Short: "Package the theme into a zip that can be uploaded to shopify",
+	Long: `Package will create a zip of your theme that can be uploaded in the shopify
+ admin or submitted to the theme store. Only files in the theme folders are
+ included and ignored files are left out the same way they are in deploy.
+
+ The theme is checked for files that would conflict when they are compiled and
+ for files that every theme needs before the zip is written. Use --output to
+ choose where the zip is written, by default it is named after the project
+ directory.
+ `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmdutil.ForLocal(flags, args, packageTheme)
+	},
+}
+
+func packageTheme(ctx *cmdutil.Ctx) error {
+	assets, err := shopify.FindAssets(ctx.Env)
+	if err != nil {
+		return err
+	}
+
+	if problemAssets := compileAssetFilenames(assets); len(problemAssets) > 0 {
+		return compiledAssetWarning(ctx.Env.Name, problemAssets)
+	}
+
+	if missing := missingThemeFiles(assets); len(missing) > 0 {
+		return fmt.Errorf("[%s] cannot package theme, missing required files: %s", colors.Green(ctx.Env.Name), strings.Join(missing, ", "))
+	}
+
+	path, err := packagePath(ctx)
+	if err != nil {
+		return err
+	}
+
+	writer, err := archive.Create(path)
+	if err != nil {
+		return err
+	}
+
+	folders := map[string]*folderSize{}
+	for _, asset := range assets {
+		data, err := ioutil.ReadFile(filepath.Join(ctx.Env.Directory, filepath.FromSlash(asset.Key)))
+		if err == nil {
+			err = writer.Add(asset.Key, data)
+		}
+		if err != nil {
+			writer.Close()
+			os.Remove(path)
+			return err
+		}
+
+		folder := strings.SplitN(asset.Key, "/", 2)[0]
+		if folders[folder] == nil {
+			folders[folder] = &folderSize{}
+		}
+		folders[folder].files++
+		folders[folder].bytes += int64(len(data))
+	}
+
+	if err := writer.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	sizeReport(ctx, folders)
+	ctx.Log.Printf("[%s] packaged %d files into %s (%s)", colors.Green(ctx.Env.Name), len(assets), colors.Blue(path), formatSize(info.Size()))
+	return nil
+}
+
+type folderSize struct {
+	files int
+	bytes int64
+}
+
+func sizeReport(ctx *cmdutil.Ctx, folders map[string]*folderSize) {
+	names := []string{}
+	for name := range folders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var total int64
+	for _, name := range names {
+		total += folders[name].bytes
+		ctx.Log.Printf("[%s] %-10s %4d files %10s", colors.Green(ctx.Env.Name), name, folders[name].files, formatSize(folders[name].bytes))
+	}
+	ctx.Log.Printf("[%s] %-10s %4s       %10s", colors.Green(ctx.Env.Name), "total", "", formatSize(total))
+}
+
+func missingThemeFiles(assets []shopify.Asset) []string {
+	found := map[string]bool{}
+	for _, asset := range assets {
+		found[asset.Key] = true
+	}
+	missing := []string{}
+	for _, key := range requiredThemeFiles {
+		if !found[key] {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// packagePath will return the path of the zip to write. If no output is set then
+// the zip is named after the project directory.
+func packagePath(ctx *cmdutil.Ctx) (string, error) {
+	if ctx.Flags.Output == "" {
+		dir, err := filepath.Abs(ctx.Env.Directory)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Base(dir) + ".zip", nil
+	} else if archive.Format(ctx.Flags.Output) != archive.Zip {
+		return "", fmt.Errorf("[%s] %s is not a .zip file", colors.Green(ctx.Env.Name), ctx.Flags.Output)
+	}
+	return ctx.Flags.Output, nil
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGT"[exp])
+}
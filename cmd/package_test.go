@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Shopify/themekit/src/archive"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+func TestPackageTheme(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "package")
+	defer os.RemoveAll(dir)
+
+	project := filepath.Join(dir, "mytheme")
+	for name, content := range map[string]string{
+		"layout/theme.liquid":         "{{ content_for_layout }}",
+		"config/settings_schema.json": "[]",
+		"assets/app.js":               "alert('hi')",
+		"assets/.DS_Store":            "junk",
+		"node_modules/dep/index.js":   "module.exports = {}",
+		"README.md":                   "readme",
+	} {
+		path := filepath.Join(project, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte(content), 0644)
+	}
+
+	ctx, _, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = project
+	ctx.Flags.Output = filepath.Join(dir, "mytheme.zip")
+	assert.Nil(t, packageTheme(ctx))
+	assert.Contains(t, stdOut.String(), "packaged 3 files into "+ctx.Flags.Output)
+	assert.Contains(t, stdOut.String(), "assets        1 files       11 B")
+
+	files := []string{}
+	assert.Nil(t, archive.Walk(ctx.Flags.Output, func(name string, data []byte) error {
+		files = append(files, name)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{"assets/app.js", "config/settings_schema.json", "layout/theme.liquid"}, files)
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Env.Directory = project
+	ctx.Flags.Output = filepath.Join(dir, "mytheme.tar.gz")
+	err := packageTheme(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "is not a .zip file")
+	}
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "analyzedir")
+	err = packageTheme(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "missing required files: config/settings_schema.json")
+	}
+
+	ctx, _, _, _, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "badprojectdir")
+	err = packageTheme(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "You have file names that will conflict with each other")
+	}
+}
+
+func TestMissingThemeFiles(t *testing.T) {
+	assert.Equal(t, []string{"layout/theme.liquid", "config/settings_schema.json"}, missingThemeFiles([]shopify.Asset{}))
+	assert.Equal(t, []string{}, missingThemeFiles([]shopify.Asset{{Key: "layout/theme.liquid"}, {Key: "config/settings_schema.json"}}))
+}
+
+func TestPackagePath(t *testing.T) {
+	ctx, _, _, _, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	path, err := packagePath(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "projectdir.zip", path)
+
+	ctx.Flags.Output = "theme.zip"
+	path, err = packagePath(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "theme.zip", path)
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "512 B", formatSize(512))
+	assert.Equal(t, "1.5 KB", formatSize(1536))
+	assert.Equal(t, "2.0 MB", formatSize(2*1024*1024))
+}
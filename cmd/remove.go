@@ -18,6 +18,9 @@ var removeCmd = &cobra.Command{
 	Short: "Remove theme file(s) from shopify",
 	Long: `Remove will delete all specified files from shopify servers.
 
+ The files that fail to be removed are saved in .themekit/failed-remove-<env>.json.
+ Pass --retry-failed to remove only those files again.
+
  For more information, refer to https://shopify.dev/tools/theme-kit/command-reference#remove.
 	`,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -30,21 +33,38 @@ var removeCmd = &cobra.Command{
 func remove(ctx *cmdutil.Ctx, removeFile func(string) error) error {
 	if ctx.Env.ReadOnly {
 		return fmt.Errorf("[%s] environment is readonly", colors.Green(ctx.Env.Name))
+	}
+
+	filenames := ctx.Args
+	if ctx.Flags.RetryFailed {
+		failures, err := loadFailures(ctx, "remove")
+		if err != nil {
+			return err
+		}
+		filenames = []string{}
+		for filename := range failures {
+			filenames = append(filenames, filename)
+		}
 	} else if len(ctx.Args) == 0 {
 		return fmt.Errorf("[%s] please specify file(s) to be removed", colors.Green(ctx.Env.Name))
 	}
 
 	var removeGroup sync.WaitGroup
-	ctx.StartProgress(len(ctx.Args))
-	for _, filename := range ctx.Args {
+	ran := map[string]file.Op{}
+	ctx.StartProgress(len(filenames))
+	for _, filename := range filenames {
+		ran[filename] = file.Remove
 		removeGroup.Add(1)
 		go func(filename string) {
 			defer removeGroup.Done()
-			perform(ctx, filename, file.Remove, "")
+			if err := perform(ctx, filename, file.Remove, ""); err != nil {
+				ctx.FailTask(filename, file.Remove)
+			}
 			removeFile(filepath.Join(ctx.Env.Directory, filename))
 		}(filename)
 	}
 
 	removeGroup.Wait()
+	saveFailures(ctx, "remove", ran)
 	return nil
 }
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/file"
+)
+
+// failedFile is the name of the file in themekitDir that the operations that
+// failed in the last run of a command are saved in, it is formatted with the name
+// of the command and the name of the environment.
+var failedFile = "failed-%s-%s.json"
+
+// failedOps are the operations that failed in the last run of a command
+type failedOps struct {
+	Command    string             `json:"command"`
+	Time       time.Time          `json:"time"`
+	Operations map[string]file.Op `json:"operations"`
+}
+
+func failedOpsPath(ctx *cmdutil.Ctx, command string) string {
+	if failedFile == "" {
+		return ""
+	}
+	return filepath.Join(ctx.Env.Directory, themekitDir, fmt.Sprintf(failedFile, command, ctx.Env.Name))
+}
+
+// saveFailures will save the operations that failed in this run of the command so
+// that they can be run again with --retry-failed. ran are the operations of this
+// run. A run of the whole theme, or of --retry-failed, replaces the failures of
+// the previous run. A run of only some files keeps the previous failures of the
+// files that were not run. If nothing is left then the saved failures are removed.
+func saveFailures(ctx *cmdutil.Ctx, command string, ran map[string]file.Op) {
+	path := failedOpsPath(ctx, command)
+	if path == "" {
+		return
+	}
+
+	failures := ctx.Failures()
+	if !ctx.Flags.RetryFailed && (len(ctx.Args) > 0 || ctx.Flags.ChangedSince != "") {
+		if previous, err := readFailures(path); err == nil {
+			for prevPath, op := range previous.Operations {
+				if _, ok := ran[prevPath]; !ok {
+					failures[prevPath] = op
+				}
+			}
+		}
+	}
+
+	if len(failures) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			ctx.ErrLog.Printf("[%s] could not remove %s: %s", colors.Green(ctx.Env.Name), colors.Blue(path), err)
+		}
+		return
+	}
+
+	data, err := json.MarshalIndent(failedOps{Command: command, Time: time.Now(), Operations: failures}, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		ctx.ErrLog.Printf("[%s] could not save the failed files to %s: %s", colors.Green(ctx.Env.Name), colors.Blue(path), err)
+		return
+	}
+	ctx.Log.Printf("[%s] %d files have failed, run %s with --retry-failed to retry them", colors.Green(ctx.Env.Name), len(failures), colors.Yellow(command))
+}
+
+// loadFailures will load the operations that failed in the last run of the command
+func loadFailures(ctx *cmdutil.Ctx, command string) (map[string]file.Op, error) {
+	if len(ctx.Args) > 0 {
+		return nil, fmt.Errorf("--retry-failed cannot be used with file names")
+	}
+
+	noFailures := fmt.Errorf("[%s] there are no failed files from the last %s to retry", colors.Green(ctx.Env.Name), command)
+	path := failedOpsPath(ctx, command)
+	if path == "" {
+		return nil, noFailures
+	}
+
+	failed, err := readFailures(path)
+	if os.IsNotExist(err) {
+		return nil, noFailures
+	} else if err != nil {
+		return nil, fmt.Errorf("[%s] could not read the failed files in %s: %s", colors.Green(ctx.Env.Name), colors.Blue(path), err)
+	} else if len(failed.Operations) == 0 {
+		return nil, noFailures
+	}
+	return failed.Operations, nil
+}
+
+func readFailures(path string) (failedOps, error) {
+	failed := failedOps{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return failed, err
+	}
+	return failed, json.Unmarshal(data, &failed)
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/cmdutil/_mocks"
+	"github.com/Shopify/themekit/src/file"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+func createFailedCtx(dir string) (*cmdutil.Ctx, *mocks.ShopifyClient) {
+	ctx, client, _, _, _ := createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.Directory = dir
+	return ctx, client
+}
+
+func TestSaveAndLoadFailures(t *testing.T) {
+	dir, cleanup := createStateProject(t, &failedFile, "failed-%s-%s.json")
+	defer cleanup()
+	path := filepath.Join(dir, themekitDir, "failed-deploy-development.json")
+
+	ctx, _ := createFailedCtx(dir)
+	_, err := loadFailures(ctx, "deploy")
+	assert.EqualError(t, err, "[development] there are no failed files from the last deploy to retry")
+
+	ctx.FailTask("assets/a.js", file.Update)
+	ctx.FailTask("assets/old.js", file.Remove)
+	saveFailures(ctx, "deploy", map[string]file.Op{})
+	_, err = os.Stat(path)
+	assert.Nil(t, err)
+
+	failures, err := loadFailures(ctx, "deploy")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]file.Op{"assets/a.js": file.Update, "assets/old.js": file.Remove}, failures)
+
+	_, err = loadFailures(ctx, "download")
+	assert.EqualError(t, err, "[development] there are no failed files from the last download to retry")
+
+	ctx.Args = []string{"assets/a.js"}
+	_, err = loadFailures(ctx, "deploy")
+	assert.EqualError(t, err, "--retry-failed cannot be used with file names")
+
+	ctx, _ = createFailedCtx(dir)
+	saveFailures(ctx, "deploy", map[string]file.Op{})
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "the failures are removed when nothing failed")
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("not json"), 0644))
+	_, err = loadFailures(ctx, "deploy")
+	assert.NotNil(t, err)
+
+	failedFile = ""
+	ctx.FailTask("assets/a.js", file.Update)
+	saveFailures(ctx, "deploy", map[string]file.Op{})
+	_, err = loadFailures(ctx, "deploy")
+	assert.EqualError(t, err, "[development] there are no failed files from the last deploy to retry")
+}
+
+func TestSaveFailuresTargetedRun(t *testing.T) {
+	dir, cleanup := createStateProject(t, &failedFile, "failed-%s-%s.json")
+	defer cleanup()
+
+	ctx, _ := createFailedCtx(dir)
+	ctx.FailTask("assets/a.js", file.Update)
+	ctx.FailTask("assets/b.js", file.Update)
+	ctx.FailTask("assets/c.js", file.Update)
+	saveFailures(ctx, "deploy", map[string]file.Op{"assets/a.js": file.Update, "assets/b.js": file.Update, "assets/c.js": file.Update})
+
+	ctx, _ = createFailedCtx(dir)
+	ctx.Args = []string{"assets/a.js", "assets/b.js"}
+	ctx.FailTask("assets/b.js", file.Update)
+	saveFailures(ctx, "deploy", map[string]file.Op{"assets/a.js": file.Update, "assets/b.js": file.Update})
+	ctx, _ = createFailedCtx(dir)
+	failures, err := loadFailures(ctx, "deploy")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]file.Op{"assets/b.js": file.Update, "assets/c.js": file.Update}, failures)
+
+	ctx, _ = createFailedCtx(dir)
+	ctx.Flags.RetryFailed = true
+	saveFailures(ctx, "deploy", map[string]file.Op{"assets/b.js": file.Update, "assets/c.js": file.Update})
+	ctx, _ = createFailedCtx(dir)
+	_, err = loadFailures(ctx, "deploy")
+	assert.EqualError(t, err, "[development] there are no failed files from the last deploy to retry")
+}
+
+func TestDeployRetryFailed(t *testing.T) {
+	dir, cleanup := createStateProject(t, &failedFile, "failed-%s-%s.json")
+	defer cleanup()
+
+	ctx, client := createFailedCtx(dir)
+	ctx.Flags.NoDelete = true
+	client.On("GetAllAssets").Return([]shopify.Asset{}, nil)
+	client.On("UpdateAsset", mock.MatchedBy(func(asset shopify.Asset) bool { return asset.Key == "assets/a.js" }), "").Return(nil).Once()
+	client.On("UpdateAsset", mock.MatchedBy(func(asset shopify.Asset) bool { return asset.Key == "assets/b.js" }), "").Return(fmt.Errorf("Liquid syntax error")).Once()
+	assert.Nil(t, deploy(ctx))
+
+	ctx, client = createFailedCtx(dir)
+	ctx.Flags.RetryFailed = true
+	client.On("UpdateAsset", mock.MatchedBy(func(asset shopify.Asset) bool { return asset.Key == "assets/b.js" }), "").Return(nil).Once()
+	assert.Nil(t, deploy(ctx))
+	client.AssertExpectations(t)
+	client.AssertNotCalled(t, "GetAllAssets")
+
+	ctx, _ = createFailedCtx(dir)
+	ctx.Flags.RetryFailed = true
+	assert.EqualError(t, deploy(ctx), "[development] there are no failed files from the last deploy to retry")
+
+	ctx.Flags.ChangedSince = "HEAD~1"
+	assert.EqualError(t, deploy(ctx), "--retry-failed cannot be used with --resume or --changed-since")
+}
+
+func TestDownloadRetryFailed(t *testing.T) {
+	dir, cleanup := createStateProject(t, &failedFile, "failed-%s-%s.json")
+	defer cleanup()
+
+	ctx, client := createFailedCtx(dir)
+	client.On("GetAllAssets").Return([]shopify.Asset{{Key: "assets/c.js", Checksum: "1"}, {Key: "assets/d.js", Checksum: "2"}}, nil)
+	client.On("GetAsset", "assets/c.js").Return(shopify.Asset{Key: "assets/c.js", Value: "c"}, nil).Once()
+	client.On("GetAsset", "assets/d.js").Return(shopify.Asset{}, fmt.Errorf("server error")).Once()
+	assert.Nil(t, download(ctx))
+
+	ctx, client = createFailedCtx(dir)
+	ctx.Flags.RetryFailed = true
+	client.On("GetAsset", "assets/d.js").Return(shopify.Asset{Key: "assets/d.js", Value: "d"}, nil).Once()
+	assert.Nil(t, download(ctx))
+	client.AssertExpectations(t)
+	client.AssertNotCalled(t, "GetAllAssets")
+	data, err := ioutil.ReadFile(filepath.Join(dir, "assets", "d.js"))
+	assert.Nil(t, err)
+	assert.Equal(t, "d", string(data))
+
+	ctx, _ = createFailedCtx(dir)
+	ctx.Flags.RetryFailed = true
+	ctx.Flags.Mirror = true
+	assert.EqualError(t, download(ctx), "--mirror cannot be used with --retry-failed")
+}
+
+func TestRemoveRetryFailed(t *testing.T) {
+	dir, cleanup := createStateProject(t, &failedFile, "failed-%s-%s.json")
+	defer cleanup()
+
+	ctx, client := createFailedCtx(dir)
+	ctx.Args = []string{"assets/a.js", "assets/b.js"}
+	client.On("DeleteAsset", shopify.Asset{Key: "assets/a.js"}).Return(nil).Once()
+	client.On("DeleteAsset", shopify.Asset{Key: "assets/b.js"}).Return(fmt.Errorf("server error")).Once()
+	assert.Nil(t, remove(ctx, func(string) error { return nil }))
+
+	ctx, client = createFailedCtx(dir)
+	ctx.Flags.RetryFailed = true
+	client.On("DeleteAsset", shopify.Asset{Key: "assets/b.js"}).Return(nil).Once()
+	removed := []string{}
+	assert.Nil(t, remove(ctx, func(path string) error {
+		removed = append(removed, path)
+		return nil
+	}))
+	client.AssertExpectations(t)
+	assert.Equal(t, []string{filepath.Join(dir, "assets/b.js")}, removed)
+
+	ctx, _ = createFailedCtx(dir)
+	ctx.Flags.RetryFailed = true
+	assert.EqualError(t, remove(ctx, os.Remove), "[development] there are no failed files from the last remove to retry")
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
+	"github.com/Shopify/themekit/src/file"
+	"github.com/Shopify/themekit/src/httpify"
+)
+
+var (
+	// retryBaseDelay is how long to wait before the first retry, the delay doubles
+	// with every attempt up to retryMaxDelay
+	retryBaseDelay = 5 * time.Second
+	retryMaxDelay  = 5 * time.Minute
+	// retryCheckInterval is how often watch checks for changes that are due to be
+	// retried
+	retryCheckInterval = time.Second
+)
+
+// retryEvent is a change that failed to upload
+type retryEvent struct {
+	Op                file.Op   `json:"op"`
+	Path              string    `json:"path"`
+	LastKnownChecksum string    `json:"last_known_checksum,omitempty"`
+	Attempts          int       `json:"attempts"`
+	Next              time.Time `json:"next"`
+	Held              bool      `json:"held,omitempty"`
+	Checksum          string    `json:"checksum,omitempty"`
+	Error             string    `json:"error"`
+}
+
+// retryQueue keeps the changes that failed in watch. Changes that failed because
+// shopify could not be reached are retried with backoff, changes that shopify
+// rejected are held until the file changes again.
+type retryQueue struct {
+	path      string
+	directory string
+	events    []*retryEvent
+}
+
+// loadRetryQueue will load the saved retry queue of an environment. Held changes
+// are released if their file changed while watch was not running.
+func loadRetryQueue(ctx *cmdutil.Ctx) *retryQueue {
+	queue := &retryQueue{
+		path:      filepath.Join(ctx.Env.Directory, themekitDir, "watch-retry-"+ctx.Env.Name+".json"),
+		directory: ctx.Env.Directory,
+		events:    []*retryEvent{},
+	}
+
+	data, err := ioutil.ReadFile(queue.path)
+	if err != nil {
+		return queue
+	} else if err := json.Unmarshal(data, &queue.events); err != nil {
+		ctx.ErrLog.Printf("[%s] could not read retry queue %s: %s", colors.Green(ctx.Env.Name), colors.Blue(queue.path), err)
+		queue.events = []*retryEvent{}
+		return queue
+	}
+
+	for _, event := range queue.events {
+		if checksum, _ := file.FileChecksum(queue.directory, event.Path); event.Held && checksum != event.Checksum {
+			event.Held = false
+			event.Next = time.Time{}
+		}
+	}
+	return queue
+}
+
+// fail will add a failed change to the queue or update it if it is already queued
+func (queue *retryQueue) fail(event file.Event, err error) {
+	queued := queue.find(event.Path)
+	if queued == nil {
+		queued = &retryEvent{Path: event.Path}
+		queue.events = append(queue.events, queued)
+	}
+	queued.Op = event.Op
+	queued.LastKnownChecksum = event.LastKnownChecksum
+	queued.Attempts++
+	queued.Error = err.Error()
+	queued.Held = !isRetryable(err)
+	if queued.Held {
+		queued.Checksum, _ = file.FileChecksum(queue.directory, event.Path)
+	} else {
+		queued.Next = time.Now().Add(retryDelay(queued.Attempts))
+	}
+	queue.save()
+}
+
+// succeed will remove a change from the queue. It returns true if the change was
+// queued.
+func (queue *retryQueue) succeed(path string) bool {
+	for i, event := range queue.events {
+		if event.Path == path {
+			queue.events = append(queue.events[:i], queue.events[i+1:]...)
+			queue.save()
+			return true
+		}
+	}
+	return false
+}
+
+// due will return the changes that should be retried now
+func (queue *retryQueue) due(now time.Time) []file.Event {
+	events := []file.Event{}
+	for _, event := range queue.events {
+		if !event.Held && !event.Next.After(now) {
+			events = append(events, file.Event{Op: event.Op, Path: event.Path, LastKnownChecksum: event.LastKnownChecksum})
+		}
+	}
+	return events
+}
+
+// counts will return how many changes are waiting to be retried and how many are
+// held until their file changes
+func (queue *retryQueue) counts() (pending, held int) {
+	for _, event := range queue.events {
+		if event.Held {
+			held++
+		} else {
+			pending++
+		}
+	}
+	return pending, held
+}
+
+// status will log how many changes have not been uploaded yet
+func (queue *retryQueue) status(ctx *cmdutil.Ctx) {
+	pending, held := queue.counts()
+	if pending+held == 0 {
+		ctx.Log.Printf("[%s] %s", colors.Green(ctx.Env.Name), colors.Green("all changes are uploaded"))
+		return
+	}
+	ctx.Log.Printf(
+		"[%s] %s changes pending retry, %s held until the file changes",
+		colors.Green(ctx.Env.Name),
+		colors.Yellow(pending),
+		colors.Red(held),
+	)
+}
+
+func (queue *retryQueue) find(path string) *retryEvent {
+	for _, event := range queue.events {
+		if event.Path == path {
+			return event
+		}
+	}
+	return nil
+}
+
+// save will write the queue to disk or remove the saved queue if it is empty
+func (queue *retryQueue) save() error {
+	if len(queue.events) == 0 {
+		if err := os.Remove(queue.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(queue.events, "", "  ")
+	if err != nil {
+		return err
+	} else if err := os.MkdirAll(filepath.Dir(queue.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(queue.path, data, 0644)
+}
+
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		return retryMaxDelay
+	}
+	return delay
+}
+
+// isRetryable will return true for errors that happened because shopify could not
+// be reached, the same request may succeed later.
+func isRetryable(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, httpify.ErrConnectionIssue) ||
+		errors.Is(err, httpify.ErrRequestFailed) ||
+		(errors.As(err, &netErr) && netErr.Timeout())
+}
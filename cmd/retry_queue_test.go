@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Shopify/themekit/src/file"
+	"github.com/Shopify/themekit/src/httpify"
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+func TestRetryQueue(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "retry")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "assets"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "assets", "app.js"), []byte("app"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "assets", "bad.js"), []byte("bad"), 0644)
+
+	ctx, _, _, stdOut, _ := createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.Directory = dir
+	queue := loadRetryQueue(ctx)
+	path := filepath.Join(dir, ".themekit", "watch-retry-development.json")
+
+	offline := fmt.Errorf("%w after 3 retries with error: timeout", httpify.ErrRequestFailed)
+	queue.fail(file.Event{Op: file.Update, Path: "assets/app.js"}, offline)
+	queue.fail(file.Event{Op: file.Update, Path: "assets/bad.js"}, fmt.Errorf("Liquid syntax error"))
+	pending, held := queue.counts()
+	assert.Equal(t, 1, pending)
+	assert.Equal(t, 1, held)
+	assert.Equal(t, 0, len(queue.due(time.Now())))
+	assert.Equal(t, []file.Event{{Op: file.Update, Path: "assets/app.js"}}, queue.due(time.Now().Add(retryBaseDelay)))
+
+	queue.fail(file.Event{Op: file.Update, Path: "assets/app.js"}, offline)
+	assert.Equal(t, 2, queue.find("assets/app.js").Attempts)
+	assert.Equal(t, 0, len(queue.due(time.Now().Add(retryBaseDelay))))
+
+	queue.status(ctx)
+	assert.Contains(t, stdOut.String(), "1 changes pending retry, 1 held until the file changes")
+
+	_, err := os.Stat(path)
+	assert.Nil(t, err)
+
+	loaded := loadRetryQueue(ctx)
+	pending, held = loaded.counts()
+	assert.Equal(t, 1, pending)
+	assert.Equal(t, 1, held)
+
+	ioutil.WriteFile(filepath.Join(dir, "assets", "bad.js"), []byte("fixed"), 0644)
+	loaded = loadRetryQueue(ctx)
+	pending, held = loaded.counts()
+	assert.Equal(t, 2, pending)
+	assert.Equal(t, 0, held)
+	assert.Equal(t, []file.Event{{Op: file.Update, Path: "assets/bad.js"}}, loaded.due(time.Now()))
+
+	assert.True(t, queue.succeed("assets/app.js"))
+	assert.False(t, queue.succeed("assets/app.js"))
+	assert.True(t, queue.succeed("assets/bad.js"))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	queue.status(ctx)
+	assert.Contains(t, stdOut.String(), "all changes are uploaded")
+
+	ioutil.WriteFile(path, []byte("{"), 0644)
+	ctx, _, _, _, stdErr := createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.Directory = dir
+	queue = loadRetryQueue(ctx)
+	assert.Equal(t, 0, len(queue.events))
+	assert.Contains(t, stdErr.String(), "could not read retry queue")
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, retryBaseDelay, retryDelay(1))
+	assert.Equal(t, 4*retryBaseDelay, retryDelay(3))
+	assert.Equal(t, retryMaxDelay, retryDelay(100))
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, isRetryable(httpify.ErrConnectionIssue))
+	assert.True(t, isRetryable(fmt.Errorf("%w after 3 retries", httpify.ErrRequestFailed)))
+	assert.True(t, isRetryable(timeoutError{}))
+	assert.False(t, isRetryable(fmt.Errorf("Liquid syntax error")))
+	assert.False(t, isRetryable(shopify.ErrNotPartOfTheme))
+}
+
+func TestWatchRetries(t *testing.T) {
+	defer func(base, interval time.Duration) {
+		retryBaseDelay, retryCheckInterval = base, interval
+	}(retryBaseDelay, retryCheckInterval)
+	retryBaseDelay, retryCheckInterval = time.Millisecond, 5*time.Millisecond
+
+	dir, _ := ioutil.TempDir("", "retry")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "assets"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "assets", "app.js"), []byte(""), 0644)
+
+	signalChan := make(chan os.Signal)
+	eventChan := make(chan file.Event)
+	ctx, client, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = dir
+	uploaded := make(chan bool)
+	client.On("UpdateAsset", mock.Anything, "").Return(httpify.ErrConnectionIssue).Once()
+	client.On("UpdateAsset", mock.Anything, "").Run(func(mock.Arguments) { uploaded <- true }).Return(nil).Once()
+	go func() {
+		eventChan <- file.Event{Op: file.Update, Path: "assets/app.js"}
+		select {
+		case <-uploaded:
+		case <-time.After(time.Second):
+			t.Error("change was never retried")
+		}
+		signalChan <- os.Interrupt
+	}()
+	assert.Nil(t, watch(ctx, eventChan, signalChan, nil, &noopNotify{}, nil))
+	assert.Contains(t, stdOut.String(), "1 changes pending retry, 0 held until the file changes")
+	assert.Contains(t, stdOut.String(), "retrying assets/app.js")
+	assert.Contains(t, stdOut.String(), "all changes are uploaded")
+	_, err := os.Stat(filepath.Join(dir, ".themekit"))
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(dir, ".themekit", "watch-retry-.json"))
+	assert.True(t, os.IsNotExist(err))
+}
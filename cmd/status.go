@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/colors"
+)
+
+// The states a file can be in when comparing the project with the remote theme
+const (
+	statusModified  = "Modified"
+	statusNew       = "New"
+	statusMissing   = "Missing"
+	statusUnchanged = "Unchanged"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the differences between your local files and the remote theme",
+	Long: `Status will compare the checksums of your local files with the files on
+ shopify and list the files that are modified or new locally and the files that
+ only exist on shopify. No files are downloaded to do this. Unchanged files are
+ listed with the --verbose flag.
+
+ Status exits with an error if there are any differences so that it can be used
+ in scripts.
+ `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// status only reads from the theme so it is safe to run on the live theme
+		flags.AllowLive = true
+		return cmdutil.ForEachClient(flags, args, status)
+	},
+}
+
+func status(ctx *cmdutil.Ctx) error {
+	ctx.DisableSummary()
+
+	statuses, err := compareAssets(ctx)
+	if err != nil {
+		return err
+	}
+
+	paths := []string{}
+	for path := range statuses {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	// the report is logged all at once so that it is not mixed up with the reports
+	// of other environments
+	counts := map[string]int{}
+	report := []string{fmt.Sprintf("[%s] theme %s", colors.Green(ctx.Env.Name), colors.Yellow(ctx.Env.ThemeID))}
+	for _, path := range paths {
+		state := statuses[path]
+		counts[state]++
+		if state != statusUnchanged || ctx.Flags.Verbose {
+			report = append(report, fmt.Sprintf("\t%s %s", statusColor(state), colors.Blue(path)))
+		}
+	}
+	report = append(report, fmt.Sprintf(
+		"[%s] %s: %d, %s: %d, %s: %d, %s: %d",
+		colors.Green(ctx.Env.Name),
+		statusColor(statusModified), counts[statusModified],
+		statusColor(statusNew), counts[statusNew],
+		statusColor(statusMissing), counts[statusMissing],
+		statusColor(statusUnchanged), counts[statusUnchanged],
+	))
+	ctx.Log.Print(strings.Join(report, "\n"))
+
+	if len(paths) != counts[statusUnchanged] {
+		return fmt.Errorf("[%s] local files do not match theme %s", colors.Green(ctx.Env.Name), ctx.Env.ThemeID)
+	}
+	return nil
+}
+
+// compareAssets will return the status of every local and remote file using only
+// the checksums from shopify.
+func compareAssets(ctx *cmdutil.Ctx) (map[string]string, error) {
+	statuses := map[string]string{}
+	checksums := map[string]string{}
+
+	remoteFiles, err := ctx.Client.GetAllAssets()
+	if err != nil {
+		return statuses, err
+	}
+	for _, remoteAsset := range remoteFiles {
+		statuses[remoteAsset.Key] = statusMissing
+		checksums[remoteAsset.Key] = remoteAsset.Checksum
+	}
+
+	localAssets, err := findChecksums(ctx)
+	if err != nil {
+		return statuses, err
+	}
+
+	for _, asset := range localAssets {
+		checksum, ok := checksums[asset.Key]
+		switch {
+		case !ok:
+			statuses[asset.Key] = statusNew
+		case asset.Checksum != "" && asset.Checksum == checksum:
+			statuses[asset.Key] = statusUnchanged
+		default:
+			statuses[asset.Key] = statusModified
+		}
+	}
+	return statuses, nil
+}
+
+func statusColor(state string) string {
+	switch state {
+	case statusModified:
+		return colors.Yellow(state)
+	case statusNew:
+		return colors.Green(state)
+	case statusMissing:
+		return colors.Red(state)
+	}
+	return colors.Cyan(state)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+func TestStatus(t *testing.T) {
+	empty := "d41d8cd98f00b204e9800998ecf8427e"
+
+	ctx, client, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	ctx.Env.ThemeID = "42"
+	client.On("GetAllAssets").Return([]shopify.Asset{
+		{Key: "assets/app.js", Checksum: empty},
+		{Key: "config/settings_data.json", Checksum: "changed"},
+		{Key: "snippets/remote.liquid", Checksum: empty},
+		{Key: "assets/app.css.liquid", Checksum: empty, Compiled: "assets/app.css"},
+	}, nil)
+	err := status(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "local files do not match theme 42")
+	}
+	assert.Contains(t, stdOut.String(), "Modified config/settings_data.json")
+	assert.Contains(t, stdOut.String(), "Missing snippets/remote.liquid")
+	assert.NotContains(t, stdOut.String(), "Unchanged assets/app.js")
+	assert.Contains(t, stdOut.String(), "Missing assets/app.css.liquid")
+	assert.NotContains(t, stdOut.String(), "assets/app.css\n")
+	assert.Contains(t, stdOut.String(), "Modified: 1, New: 0, Missing: 2, Unchanged: 1")
+	client.AssertNotCalled(t, "GetAsset", "config/settings_data.json")
+
+	ctx, client, _, stdOut, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	ctx.Flags.Verbose = true
+	client.On("GetAllAssets").Return([]shopify.Asset{
+		{Key: "assets/app.js", Checksum: empty},
+		{Key: "config/settings_data.json", Checksum: empty},
+	}, nil)
+	assert.Nil(t, status(ctx))
+	assert.Contains(t, stdOut.String(), "Unchanged assets/app.js")
+	assert.Contains(t, stdOut.String(), "Modified: 0, New: 0, Missing: 0, Unchanged: 2")
+
+	ctx, client, _, stdOut, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	client.On("GetAllAssets").Return([]shopify.Asset{{Key: "assets/app.js", Checksum: empty}}, nil)
+	assert.NotNil(t, status(ctx))
+	assert.Contains(t, stdOut.String(), "New config/settings_data.json")
+
+	ctx, client, _, _, _ = createTestCtx()
+	client.On("GetAllAssets").Return([]shopify.Asset{}, fmt.Errorf("server error"))
+	assert.EqualError(t, status(ctx), "server error")
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Env.Directory = "nope"
+	client.On("GetAllAssets").Return([]shopify.Asset{}, nil)
+	assert.NotNil(t, status(ctx))
+}
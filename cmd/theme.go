@@ -4,9 +4,11 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/Shopify/themekit/src/archive"
 	"github.com/Shopify/themekit/src/cmdutil"
 	"github.com/Shopify/themekit/src/colors"
 	"github.com/Shopify/themekit/src/env"
@@ -105,33 +107,77 @@ func init() {
 	ThemeCmd.PersistentFlags().BoolVar(&flags.AllowLive, "allow-live", false, "Will allow themekit to make changes to the live theme on the store.")
 	ThemeCmd.PersistentFlags().BoolVarP(&flags.DisableThemeKitAccessNotifier, "no-theme-kit-access-notifier", "", false, "Stop theme kit from notifying about Theme Access.")
 
-	watchCmd.Flags().StringVarP(&flags.Notify, "notify", "n", "", "file to touch or url to notify when a file has been changed")
+	watchCmd.Flags().StringVarP(&flags.Notify, "notify", "n", "", "file to touch, url to notify or exec:<command> to run when files have changed")
 	watchCmd.Flags().BoolVarP(&flags.AllEnvs, "allenvs", "a", false, "run command with all environments")
+	watchCmd.Flags().BoolVar(&flags.TwoWay, "two-way", false, "also download files that are changed on shopify")
+	watchCmd.Flags().DurationVar(&flags.PollInterval, "poll-interval", 10*time.Second, "how often to check shopify for changes with --two-way")
+	watchCmd.Flags().BoolVar(&flags.SyncOnStart, "sync-on-start", false, "upload files that changed while watch was not running before watching")
+	watchCmd.Flags().BoolVar(&flags.Delete, "delete", false, "also delete files from shopify that do not exist locally with --sync-on-start")
+	watchCmd.Flags().BoolVar(&flags.LiveReload, "live-reload", false, "start a live reload server that reloads the browser after files are uploaded")
+	watchCmd.Flags().StringVar(&flags.LiveReloadAddr, "live-reload-addr", "localhost:35729", "the address the live reload server listens on")
+	watchCmd.Flags().BoolVar(&flags.Interactive, "interactive", false, "read commands to pause, resume and sync while watching")
+	watchCmd.Flags().StringVar(&flags.StatusFile, "status-file", "", "write the status of watch as json to this file")
+	watchCmd.Flags().StringVar(&flags.StatusAddr, "status-addr", "", "serve the status of watch as json at /status on this address")
 	removeCmd.Flags().BoolVarP(&flags.AllEnvs, "allenvs", "a", false, "run command with all environments")
 	openCmd.Flags().BoolVarP(&flags.AllEnvs, "allenvs", "a", false, "run command with all environments")
 	downloadCmd.Flags().BoolVarP(&flags.AllEnvs, "allenvs", "a", false, "run command with all environments")
 	deployCmd.Flags().BoolVarP(&flags.AllEnvs, "allenvs", "a", false, "run command with all environments")
+	statusCmd.Flags().BoolVarP(&flags.AllEnvs, "allenvs", "a", false, "run command with all environments")
+	checksumCmd.Flags().BoolVarP(&flags.AllEnvs, "allenvs", "a", false, "run command with all environments")
 	updateCmd.Flags().StringVar(&flags.Version, "version", "latest", "version of themekit to install")
 	newCmd.Flags().StringVarP(&flags.Name, "name", "n", "", "a name to define your theme on your shopify admin")
+	newCmd.Flags().StringVar(&flags.From, "from", "", "a url to a theme zip for shopify to import, or a local zip, tar.gz or directory to start the theme from")
+	newCmd.Flags().StringVar(&flags.Template, "template", "", "a starter name, directory, zip or git url to scaffold the theme from")
+	newCmd.Flags().StringVar(&flags.Role, "role", "", "the role of a theme imported from a url, main or unpublished")
 	openCmd.Flags().BoolVarP(&flags.Edit, "edit", "E", false, "open the web editor for the theme.")
 	openCmd.Flags().StringVarP(&flags.With, "browser", "b", "", "name of the browser to open the url. the name should match the name of browser on your system.")
 	getCmd.Flags().BoolVarP(&flags.List, "list", "l", false, "list available themes.")
 	deployCmd.Flags().BoolVarP(&flags.NoDelete, "nodelete", "n", false, "do not delete files on shopify during deploy.")
+	deployCmd.Flags().BoolVar(&flags.Merge, "merge", false, "merge settings_data.json and JSON templates with the versions on shopify instead of overwriting them, values changed both locally and on shopify keep the shopify value.")
+	deployCmd.Flags().BoolVar(&flags.DryRun, "dry-run", false, "list the changes that deploy would make without making them.")
+	deployCmd.Flags().StringVar(&flags.ChangedSince, "changed-since", "", "only deploy files that git reports as changed since this revision.")
+	deployCmd.Flags().BoolVar(&flags.RetryFailed, "retry-failed", false, "only deploy the files that failed in the last deploy.")
+	deployCmd.Flags().BoolVar(&flags.Resume, "resume", false, "continue a deploy that was interrupted or had errors, skipping the files that were already deployed.")
 	openCmd.Flags().BoolVar(&flags.HidePreviewBar, "hidepb", false, "run command with all environments")
 
 	getCmd.Flags().BoolVar(&flags.Live, "live", false, "will allow themekit to autofill the theme ID as the currently published theme ID")
+	downloadCmd.Flags().BoolVar(&flags.RetryFailed, "retry-failed", false, "only download the files that failed in the last download.")
+	removeCmd.Flags().BoolVar(&flags.RetryFailed, "retry-failed", false, "only remove the files that failed in the last remove.")
+	downloadCmd.Flags().BoolVar(&flags.Mirror, "mirror", false, "remove local files that do not exist on shopify.")
+	downloadCmd.Flags().BoolVar(&flags.DryRun, "dry-run", false, "list the files that download would change without changing them.")
 	downloadCmd.Flags().BoolVar(&flags.Live, "live", false, "will allow themekit to autofill the theme ID as the currently published theme ID")
 	configureCmd.Flags().BoolVar(&flags.Live, "live", false, "will allow themekit to autofill the theme ID as the currently published theme ID")
 
+	localesExportCmd.Flags().BoolVar(&flags.CSV, "csv", false, "export translations as csv")
+	localesExportCmd.Flags().BoolVar(&flags.Schema, "schema", false, "export the schema locales instead of the storefront locales")
+	localesExportCmd.Flags().StringVarP(&flags.Output, "output", "o", "", "file to write the export to, defaults to printing it")
+	analyzeCmd.Flags().BoolVar(&flags.Dot, "dot", false, "print the dependency graph in the graphviz dot format")
+	analyzeCmd.Flags().StringVarP(&flags.Output, "output", "o", "", "file to write the dot graph to, defaults to printing it")
+	backupCmd.Flags().StringVar(&flags.Format, "format", archive.Zip, "archive format of the backup, zip or tar.gz")
+	backupCmd.Flags().StringVarP(&flags.Output, "output", "o", "", "directory or archive file to write the backup to")
+	restoreCmd.Flags().StringVar(&flags.Name, "name", "", "create a new theme with this name and restore into it")
+	restoreCmd.Flags().BoolVar(&flags.DryRun, "dry-run", false, "verify the archive and list the files that would be uploaded without uploading them.")
+	packageCmd.Flags().StringVarP(&flags.Output, "output", "o", "", "zip file to write the theme to, defaults to the name of the project directory")
+	generateCmd.AddCommand(generateSectionCmd, generateSnippetCmd, generateTemplateCmd, generateLocaleCmd)
+	localesCmd.AddCommand(localesCheckCmd, localesExportCmd, localesImportCmd)
+
 	ThemeCmd.AddCommand(
+		analyzeCmd,
+		backupCmd,
+		checksumCmd,
 		configureCmd,
 		deployCmd,
 		downloadCmd,
+		generateCmd,
 		getCmd,
+		localesCmd,
 		newCmd,
 		openCmd,
+		packageCmd,
 		publishCmd,
 		removeCmd,
+		restoreCmd,
+		statusCmd,
 		updateCmd,
 		versionCmd,
 		watchCmd,
@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -23,6 +28,10 @@ const assetLimit = 100
 // are uploaded.
 var assetLimitSemaphore = make(chan struct{}, assetLimit)
 
+// minPollInterval is the shortest interval that two-way watch will poll shopify
+// for changes so that polling does not use up the rate limit needed for uploads.
+const minPollInterval = 2 * time.Second
+
 var watchCmd = &cobra.Command{
 	Use:   "watch",
 	Short: "Watch directory for changes and update remote theme",
@@ -30,12 +39,101 @@ var watchCmd = &cobra.Command{
 
  run 'theme watch' while you are editing and it will detect create, update and delete events.
 
+ When files or folders are renamed or moved, the files at their new paths are
+ uploaded before the old paths are removed so that the theme never misses a file.
+ If a file at a new path fails to upload, its old path is kept until it uploads.
+
+ With --two-way, watch will also poll shopify for files that were changed in the
+ online editor and download them. If a file was changed both locally and on
+ shopify, the remote version is written next to it as <file>.remote so that no
+ work is lost. The interval between polls is set with --poll-interval.
+
+ With --sync-on-start, files that were changed while watch was not running, for
+ example after checking out another branch, are uploaded before watching starts
+ the same way as 'theme deploy --nodelete'. Add --delete to also remove files from
+ shopify that do not exist locally.
+
+ With --live-reload, watch starts a server that browsers connect to with a small
+ script, <script src="http://localhost:35729/livereload.js"></script>, added to
+ the theme layout while developing. The page is reloaded once after each batch of
+ uploads and stylesheets are swapped without a reload if only css changed. Use
+ --live-reload-addr to change the address the server listens on.
+
+ Notify targets are set with --notify or with notify and notify_targets in the
+ config. A target can be a file to touch, a url or exec:<command> to run a local
+ command. Urls and commands get one json payload for each batch of changes with
+ the environment, theme, store and the files that changed. If notify_secret is
+ set, url payloads are signed with an HMAC-SHA256 in the X-Themekit-Signature
+ header.
+
+ With --interactive, watch reads commands while it runs. Press the key of a
+ command, enter is not needed when watch runs in a terminal:
+
+   p  pause uploading, changes are queued until watch is resumed
+   r  resume uploading and upload the queued changes
+   s  force a sync of all local changes, like --sync-on-start
+   e  show the recent errors
+   f  upload the last file that failed again
+   n  switch which environment's output is shown when using --allenvs
+   h  show the commands
+
+ Commands apply to the environment that is shown, or all of them if all are
+ shown. Watch can also be paused with SIGUSR1 and resumed with SIGUSR2 when it
+ is not run interactively.
+
+ Changes that fail to upload because shopify could not be reached are retried
+ with backoff until they succeed. Changes that shopify rejects are held until
+ the file changes again. Failed changes are saved in the .themekit directory of
+ the project so that they are retried the next time watch runs.
+
+ With --status-file, watch writes its status as json to a file every time it
+ changes. With --status-addr, the same status is served at /status on that
+ address. The status has whether watch is running, the environment and theme,
+ the last time a file was synced, the queued and failed changes with the last
+ error of each file and the state of the rate limit, so that editors and other
+ tools can show whether the theme is in sync.
+
  For more information, refer to https://shopify.dev/tools/theme-kit/command-reference#watch.
  `,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		var reloader *liveReload
+		if flags.LiveReload {
+			var err error
+			if reloader, err = startLiveReload(flags.LiveReloadAddr); err != nil {
+				return err
+			}
+			defer reloader.Close()
+		}
+
+		status := newWatchStatus(flags.StatusFile)
+		defer status.close()
+		if flags.StatusAddr != "" {
+			if err := status.listen(flags.StatusAddr); err != nil {
+				return err
+			}
+		}
+
+		console := newWatchConsole()
+		stopSignals := console.handleSignals()
+		defer stopSignals()
+		if flags.Interactive {
+			restore := keystrokeMode(os.Stdin)
+			defer restore()
+			go console.run(os.Stdin, colors.ColorStdOut)
+		}
+
 		return cmdutil.ForEachClient(flags, args, func(ctx *cmdutil.Ctx) error {
 			ctx.DisableSummary()
 
+			if ctx.Flags.SyncOnStart {
+				if err := syncLocalChanges(ctx); err != nil {
+					return err
+				}
+			}
+
+			// the checksums from shopify are compared with local files using the same
+			// checksums that shopify calculates, so that json is not a change when only
+			// its whitespace differs
 			checksums := map[string]string{}
 			remoteFiles, err := ctx.Client.GetAllAssets()
 			if err != nil {
@@ -49,20 +147,48 @@ var watchCmd = &cobra.Command{
 			if err != nil {
 				return err
 			}
+			watcher.SetChecksumFunc(func(dir, path string) (string, error) {
+				asset, err := shopify.ReadAsset(ctx.Env, path)
+				return asset.Checksum, err
+			})
 			watcher.Watch()
 			defer watcher.Stop()
 
+			var remote *remoteChecksums
+			if ctx.Flags.TwoWay {
+				remote = newRemoteChecksums(checksums)
+				done := make(chan struct{})
+				defer close(done)
+				go pollRemote(ctx, watcher, remote, done)
+			}
+
 			signalChan := make(chan os.Signal, 1)
 			signal.Notify(signalChan, os.Interrupt)
 
-			notifier := newNotifyAdapter(ctx.Env.Notify)
+			controls := console.register(ctx.Env.Name)
+			if ctx.Flags.Interactive {
+				ctx.Log = log.New(console.output(ctx.Env.Name, ctx.Log.Writer()), ctx.Log.Prefix(), ctx.Log.Flags())
+			}
 
-			return watch(ctx, watcher.Events, signalChan, notifier)
+			notifier := newNotifyAdapters(ctx.Env)
+			if reloader != nil {
+				ctx.Log.Printf(
+					"[%s] live reload is running, add %s to your layout to reload the browser",
+					colors.Green(ctx.Env.Name),
+					colors.Blue(fmt.Sprintf(`<script src="http://%s/livereload.js"></script>`, ctx.Flags.LiveReloadAddr)),
+				)
+				notifier = multiNotify{notifier, reloader}
+			}
+			if remote != nil {
+				notifier = multiNotify{notifier, remote}
+			}
+
+			return watch(ctx, watcher.Events, signalChan, controls, notifier, status)
 		})
 	},
 }
 
-func watch(ctx *cmdutil.Ctx, events chan file.Event, sig chan os.Signal, notifier notifyAdapter) error {
+func watch(ctx *cmdutil.Ctx, events chan file.Event, sig chan os.Signal, controls chan watchControl, notifier notifyAdapter, status *watchStatus) error {
 	// watch should output every action that it is taking and not use a progress bar
 	ctx.Flags.Verbose = true
 	ctx.Log.SetFlags(log.Ltime)
@@ -77,17 +203,118 @@ func watch(ctx *cmdutil.Ctx, events chan file.Event, sig chan os.Signal, notifie
 		colors.Yellow(ctx.Shop.Name),
 		colors.Yellow(ctx.Env.ThemeID),
 	)
+
+	retries := loadRetryQueue(ctx)
+	if pending, held := retries.counts(); pending+held > 0 {
+		retries.status(ctx)
+	}
+	retryTicker := time.NewTicker(retryCheckInterval)
+	defer retryTicker.Stop()
+
+	paused := false
+	queued := []file.Event{}
+	var lastFailed *file.Event
+	reportQueues := func() {
+		pending, held := retries.counts()
+		status.queues(ctx, paused, len(queued), pending, held)
+	}
+	status.started(ctx)
+	reportQueues()
+	// heldRemoves are the removes of renamed or moved files whose new path failed to
+	// upload, by the new path. They are carried out once the new path succeeds.
+	heldRemoves := map[string]file.Event{}
+	var process func(event file.Event)
+	process = func(event file.Event) {
+		if event.Op == file.Remove && event.MovedTo != "" && retries.find(event.MovedTo) != nil {
+			heldRemoves[event.MovedTo] = event
+			ctx.Log.Printf("[%s] %s was kept on shopify until %s is uploaded", colors.Green(ctx.Env.Name), colors.Blue(event.Path), colors.Blue(event.MovedTo))
+			return
+		}
+		ctx.Log.Printf("[%s] processing %s", colors.Green(ctx.Env.Name), colors.Blue(event.Path))
+		if err := perform(ctx, event.Path, event.Op, event.LastKnownChecksum); err != nil && event.Op == file.Remove && errors.Is(err, shopify.ErrCriticalFile) {
+			// the file was renamed or moved and its new path was already uploaded,
+			// shopify will not remove the old path so it is kept
+			ctx.Log.Printf("[%s] %s was kept on shopify because it is required by the theme", colors.Green(ctx.Env.Name), colors.Blue(event.Path))
+		} else if err != nil {
+			lastFailed = &event
+			status.failed(ctx, event.Path, err)
+			if shouldRetry(ctx, event, err) {
+				retries.fail(event, err)
+				retries.status(ctx)
+			}
+		} else {
+			status.succeeded(ctx, event.Path)
+			if lastFailed != nil && lastFailed.Path == event.Path {
+				lastFailed = nil
+			}
+			if retries.succeed(event.Path) {
+				retries.status(ctx)
+			}
+			// only changes that are live on shopify are sent to notify targets and
+			// reload the browser
+			if event.Op != file.Skip {
+				notifier.notify(ctx, event)
+			}
+		}
+		reportQueues()
+		if remove, ok := heldRemoves[event.Path]; ok && retries.find(event.Path) == nil {
+			delete(heldRemoves, event.Path)
+			process(remove)
+		}
+	}
+
 	for {
 		select {
 		case event := <-events:
 			if event.Path == ctx.Flags.ConfigPath {
 				ctx.Log.Print("Reloading config changes")
 				return cmdutil.ErrReload
+			} else if paused {
+				queued = queueEvent(queued, event)
+				ctx.Log.Printf("[%s] paused, queued %s", colors.Green(ctx.Env.Name), colors.Blue(event.Path))
+				reportQueues()
+				continue
 			}
-			ctx.Log.Printf("[%s] processing %s", colors.Green(ctx.Env.Name), colors.Blue(event.Path))
-			perform(ctx, event.Path, event.Op, event.LastKnownChecksum)
-			if event.Op != file.Skip {
-				notifier.notify(ctx, event.Path)
+			process(event)
+		case control := <-controls:
+			switch control {
+			case watchPause:
+				if !paused {
+					paused = true
+					ctx.Log.Printf("[%s] %s, changes will be queued until watch is resumed", colors.Green(ctx.Env.Name), colors.Yellow("Paused"))
+					reportQueues()
+				}
+			case watchResume:
+				if paused {
+					paused = false
+					ctx.Log.Printf("[%s] %s, processing %d queued changes", colors.Green(ctx.Env.Name), colors.Green("Resumed"), len(queued))
+					file.SortEvents(queued)
+					for _, event := range queued {
+						process(event)
+					}
+					queued = []file.Event{}
+					reportQueues()
+				}
+			case watchSync:
+				if err := syncLocalChanges(ctx); err != nil {
+					ctx.Err("[%s] error syncing: %s", colors.Green(ctx.Env.Name), err)
+				}
+			case watchErrors:
+				showRecentErrors(ctx)
+			case watchRetry:
+				if lastFailed == nil {
+					ctx.Log.Printf("[%s] there are no failed files to retry", colors.Green(ctx.Env.Name))
+				} else {
+					process(*lastFailed)
+				}
+			}
+		case <-retryTicker.C:
+			if paused {
+				continue
+			}
+			for _, event := range retries.due(time.Now()) {
+				ctx.Log.Printf("[%s] retrying %s", colors.Green(ctx.Env.Name), colors.Blue(event.Path))
+				process(event)
 			}
 		case <-sig:
 			return nil
@@ -95,7 +322,84 @@ func watch(ctx *cmdutil.Ctx, events chan file.Event, sig chan os.Signal, notifie
 	}
 }
 
-func perform(ctx *cmdutil.Ctx, path string, op file.Op, checksum string) {
+// shouldRetry will return true if a failed change should be added to the retry
+// queue. Changes to files that no longer exist and deletes that shopify rejected
+// will not succeed by trying again.
+func shouldRetry(ctx *cmdutil.Ctx, event file.Event, err error) bool {
+	if isRetryable(err) {
+		return true
+	} else if event.Op != file.Update {
+		return false
+	}
+	_, statErr := os.Stat(filepath.Join(ctx.Env.Directory, filepath.FromSlash(event.Path)))
+	return statErr == nil
+}
+
+// queueEvent will add an event to the queue of a paused watch. Only the latest
+// event for a file is kept so that intermediate states are never uploaded.
+func queueEvent(queued []file.Event, event file.Event) []file.Event {
+	for i, queuedEvent := range queued {
+		if queuedEvent.Path == event.Path {
+			queued = append(queued[:i], queued[i+1:]...)
+			break
+		}
+	}
+	return append(queued, event)
+}
+
+// recentErrorCount is how many errors are shown by the errors console command
+const recentErrorCount = 10
+
+func showRecentErrors(ctx *cmdutil.Ctx) {
+	errs := ctx.Errors()
+	if len(errs) == 0 {
+		ctx.Log.Printf("[%s] no errors", colors.Green(ctx.Env.Name))
+		return
+	} else if len(errs) > recentErrorCount {
+		errs = errs[len(errs)-recentErrorCount:]
+	}
+	ctx.Log.Printf("[%s] %s", colors.Green(ctx.Env.Name), colors.Red("Recent errors:"))
+	for _, msg := range errs {
+		ctx.Log.Printf("\t%s", msg)
+	}
+}
+
+// syncLocalChanges will upload any local changes that were made while watch was
+// not running or while it was paused, using the same plan as deploy.
+func syncLocalChanges(ctx *cmdutil.Ctx) error {
+	if ctx.Env.ReadOnly {
+		return fmt.Errorf("[%s] environment is readonly", colors.Green(ctx.Env.Name))
+	}
+
+	ctx.Flags.NoDelete = !ctx.Flags.Delete
+	assetsActions, err := generateActions(ctx)
+	if err != nil {
+		return err
+	}
+	skipCompiledOutputs(ctx, assetsActions)
+
+	changes := map[string]file.Op{}
+	for path, op := range assetsActions {
+		if op != file.Skip {
+			changes[path] = op
+		}
+	}
+
+	if len(changes) == 0 {
+		ctx.Log.Printf("[%s] local files are in sync with theme %s", colors.Green(ctx.Env.Name), colors.Yellow(ctx.Env.ThemeID))
+		return nil
+	}
+
+	ctx.Log.Printf("[%s] syncing %d local changes to theme %s", colors.Green(ctx.Env.Name), len(changes), colors.Yellow(ctx.Env.ThemeID))
+	// every change is logged the same way that watch logs them
+	ctx.Flags.Verbose = true
+	applyActions(ctx, changes, nil)
+	return nil
+}
+
+// perform will carry out a single file operation. If the operation fails the
+// error is reported with ctx.Err and also returned.
+func perform(ctx *cmdutil.Ctx, path string, op file.Op, checksum string) (err error) {
 	defer ctx.DoneTask(op)
 
 	switch op {
@@ -108,31 +412,187 @@ func perform(ctx *cmdutil.Ctx, path string, op file.Op, checksum string) {
 	case file.Remove:
 		if err := ctx.Client.DeleteAsset(shopify.Asset{Key: path}); err != nil {
 			ctx.Err("[%s] (%s) %s", colors.Green(ctx.Env.Name), colors.Blue(path), err)
+			return err
 		} else if ctx.Flags.Verbose {
 			ctx.Log.Printf("[%s] Deleted %s", colors.Green(ctx.Env.Name), colors.Blue(path))
 		}
 	case file.Get:
 		if asset, err := ctx.Client.GetAsset(path); err != nil {
 			ctx.Err("[%s] error downloading %s: %s", colors.Green(ctx.Env.Name), colors.Blue(path), err)
+			return err
 		} else if err = asset.Write(ctx.Env.Directory); err != nil {
 			ctx.Err("[%s] error writing %s: %s", colors.Green(ctx.Env.Name), colors.Blue(asset.Key), err)
-		} else if ctx.Flags.Verbose {
-			ctx.Log.Printf("[%s] Successfully wrote %s to disk", colors.Green(ctx.Env.Name), colors.Blue(asset.Key))
+			return err
+		} else {
+			saveMergeBase(ctx, asset)
+			if ctx.Flags.Verbose {
+				ctx.Log.Printf("[%s] Successfully wrote %s to disk", colors.Green(ctx.Env.Name), colors.Blue(asset.Key))
+			}
 		}
 	default:
 		assetLimitSemaphore <- struct{}{}
 		defer func() { <-assetLimitSemaphore }()
 
-		asset, err := shopify.ReadAsset(ctx.Env, path)
+		var asset shopify.Asset
+		var err error
+		if ctx.Flags.Merge && isMergeable(path) {
+			asset, err = mergedAsset(ctx, path)
+		} else {
+			asset, err = shopify.ReadAsset(ctx.Env, path)
+		}
 		if err != nil {
 			ctx.Err("[%s] error loading %s: %s", colors.Green(ctx.Env.Name), colors.Green(path), colors.Red(err))
+			return err
+		}
+
+		if err := upload(ctx, asset, checksum); err != nil {
+			return err
+		}
+		saveMergeBase(ctx, asset)
+	}
+	return nil
+}
+
+func upload(ctx *cmdutil.Ctx, asset shopify.Asset, checksum string) error {
+	if err := ctx.Client.UpdateAsset(asset, checksum); err != nil {
+		ctx.Err("[%s] (%s) %s", colors.Green(ctx.Env.Name), colors.Blue(asset.Key), err)
+		return err
+	} else if ctx.Flags.Verbose {
+		ctx.Log.Printf("[%s] Updated %s", colors.Green(ctx.Env.Name), colors.Blue(asset.Key))
+	}
+	return nil
+}
+
+// checksumStore keeps the last known checksums of the local files so that files
+// downloaded by two-way watch are not uploaded again.
+type checksumStore interface {
+	Checksum(path string) string
+	SetChecksum(path, checksum string)
+}
+
+// remoteChecksums keeps the checksums of the files on shopify as two-way watch
+// last saw them. Changes uploaded by watch are recorded through notify so that
+// they are not downloaded again as remote changes.
+type remoteChecksums struct {
+	mu        sync.Mutex
+	checksums map[string]string
+}
+
+func newRemoteChecksums(checksums map[string]string) *remoteChecksums {
+	remote := &remoteChecksums{checksums: map[string]string{}}
+	for key, checksum := range checksums {
+		remote.checksums[key] = checksum
+	}
+	return remote
+}
+
+func (remote *remoteChecksums) get(path string) string {
+	remote.mu.Lock()
+	defer remote.mu.Unlock()
+	return remote.checksums[path]
+}
+
+func (remote *remoteChecksums) set(path, checksum string) {
+	remote.mu.Lock()
+	defer remote.mu.Unlock()
+	remote.checksums[path] = checksum
+}
+
+func (remote *remoteChecksums) notify(ctx *cmdutil.Ctx, event file.Event) {
+	switch event.Op {
+	case file.Remove:
+		remote.mu.Lock()
+		defer remote.mu.Unlock()
+		delete(remote.checksums, event.Path)
+	case file.Update:
+		if asset, err := shopify.ReadAsset(ctx.Env, event.Path); err == nil {
+			remote.set(event.Path, asset.Checksum)
+		}
+	}
+}
+
+func pollRemote(ctx *cmdutil.Ctx, store checksumStore, remote *remoteChecksums, done chan struct{}) {
+	interval := ctx.Flags.PollInterval
+	if interval < minPollInterval {
+		interval = minPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			pullRemoteChanges(ctx, store, remote)
+		case <-done:
 			return
 		}
+	}
+}
+
+// pullRemoteChanges will download every file whose checksum on shopify changed since
+// the last time it was checked. remote is updated with the new checksums.
+func pullRemoteChanges(ctx *cmdutil.Ctx, store checksumStore, remote *remoteChecksums) {
+	remoteFiles, err := ctx.Client.GetAllAssets()
+	if err != nil {
+		ctx.ErrLog.Printf("[%s] error checking for remote changes: %s", colors.Green(ctx.Env.Name), err)
+		return
+	}
+	for _, remoteAsset := range remoteFiles {
+		if remoteAsset.Checksum == remote.get(remoteAsset.Key) {
+			continue
+		}
+		if pullAsset(ctx, store, remoteAsset.Key) {
+			remote.set(remoteAsset.Key, remoteAsset.Checksum)
+		}
+	}
+}
 
-		if err = ctx.Client.UpdateAsset(asset, checksum); err != nil {
-			ctx.Err("[%s] (%s) %s", colors.Green(ctx.Env.Name), colors.Blue(asset.Key), err)
-		} else if ctx.Flags.Verbose {
-			ctx.Log.Printf("[%s] Updated %s", colors.Green(ctx.Env.Name), colors.Blue(asset.Key))
+// pullAsset will download a file that changed on shopify. If the local file was
+// also changed since it was last synced then the remote version is written to
+// <path>.remote instead. It returns false if the file should be tried again.
+func pullAsset(ctx *cmdutil.Ctx, store checksumStore, path string) bool {
+	asset, err := ctx.Client.GetAsset(path)
+	if err != nil {
+		ctx.Err("[%s] error downloading %s: %s", colors.Green(ctx.Env.Name), colors.Blue(path), err)
+		return false
+	}
+	contents, err := asset.Contents()
+	if err != nil {
+		ctx.Err("[%s] error downloading %s: %s", colors.Green(ctx.Env.Name), colors.Blue(path), err)
+		return false
+	}
+
+	remoteChecksum := asset.Checksum
+	if remoteChecksum == "" {
+		remoteChecksum = shopify.NewAssetForEnv(ctx.Env, path, contents).Checksum
+	}
+	local, err := ioutil.ReadFile(filepath.Join(ctx.Env.Directory, filepath.FromSlash(path)))
+	localChecksum := shopify.NewAssetForEnv(ctx.Env, path, local).Checksum
+	if err == nil && localChecksum == remoteChecksum {
+		// the change was most likely made by this watch uploading the file
+		return true
+	}
+
+	if err == nil && localChecksum != store.Checksum(path) {
+		conflict := asset
+		conflict.Key = path + ".remote"
+		if err := conflict.Write(ctx.Env.Directory); err != nil {
+			ctx.Err("[%s] error writing %s: %s", colors.Green(ctx.Env.Name), colors.Blue(conflict.Key), err)
+			return false
 		}
+		ctx.ErrLog.Printf(
+			"[%s] %s %s was changed locally and on shopify, the remote version was written to %s",
+			colors.Green(ctx.Env.Name), colors.Yellow("Conflict"), colors.Blue(path), colors.Blue(conflict.Key),
+		)
+		return true
+	}
+
+	// the checksum is set before writing so that the write is skipped by the watcher
+	store.SetChecksum(path, shopify.NewAssetForEnv(ctx.Env, path, contents).Checksum)
+	if err := asset.Write(ctx.Env.Directory); err != nil {
+		ctx.Err("[%s] error writing %s: %s", colors.Green(ctx.Env.Name), colors.Blue(path), err)
+		return false
 	}
+	saveMergeBase(ctx, asset)
+	ctx.Log.Printf("[%s] Downloaded remote changes to %s", colors.Green(ctx.Env.Name), colors.Blue(path))
+	return true
 }
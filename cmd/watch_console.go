@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"github.com/Shopify/themekit/src/colors"
+)
+
+// watchControl is a command sent to a running watch
+type watchControl int
+
+const (
+	watchPause watchControl = iota
+	watchResume
+	watchSync
+	watchErrors
+	watchRetry
+)
+
+const watchConsoleHelp = `Commands:
+  p  pause uploading
+  r  resume uploading
+  s  sync all local changes
+  e  show recent errors
+  f  retry the last failed file
+  n  switch the environment that is shown
+  h  show this help`
+
+var watchConsoleCommands = map[string]watchControl{
+	"p": watchPause,
+	"r": watchResume,
+	"s": watchSync,
+	"e": watchErrors,
+	"f": watchRetry,
+}
+
+// watchConsole sends commands to the watches of every environment, either from
+// stdin or from signals, and controls which environment's output is shown.
+type watchConsole struct {
+	mu       sync.Mutex
+	envs     []string
+	controls map[string]chan watchControl
+	shown    string
+}
+
+func newWatchConsole() *watchConsole {
+	return &watchConsole{controls: map[string]chan watchControl{}}
+}
+
+// register will return the channel that a watch for an environment receives
+// its commands from.
+func (console *watchConsole) register(name string) chan watchControl {
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	if _, ok := console.controls[name]; !ok {
+		console.envs = append(console.envs, name)
+		console.controls[name] = make(chan watchControl, 10)
+	}
+	return console.controls[name]
+}
+
+// send will send a command to the shown environment or to all of them if all
+// environments are shown.
+func (console *watchConsole) send(control watchControl) {
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	for name, controls := range console.controls {
+		if console.shown == "" || console.shown == name {
+			select {
+			case controls <- control:
+			default:
+				// the watch is busy with earlier commands
+			}
+		}
+	}
+}
+
+// next will switch the output to the next environment, after the last
+// environment all of them are shown again.
+func (console *watchConsole) next() string {
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	index := 0
+	for i, name := range console.envs {
+		if console.shown != "" && name == console.shown {
+			index = i + 1
+		}
+	}
+	if index >= len(console.envs) {
+		console.shown = ""
+	} else {
+		console.shown = console.envs[index]
+	}
+	return console.shown
+}
+
+func (console *watchConsole) isShown(name string) bool {
+	console.mu.Lock()
+	defer console.mu.Unlock()
+	return console.shown == "" || console.shown == name
+}
+
+// run will read commands from input until it is closed. Every key is a command,
+// whitespace like the enter key is ignored.
+func (console *watchConsole) run(input io.Reader, out *log.Logger) {
+	reader := bufio.NewReader(input)
+	for {
+		key, _, err := reader.ReadRune()
+		if err != nil {
+			return
+		}
+		command := strings.ToLower(string(key))
+		if control, ok := watchConsoleCommands[command]; ok {
+			console.send(control)
+			continue
+		}
+		switch command {
+		case " ", "\t", "\r", "\n":
+		case "n":
+			if shown := console.next(); shown == "" {
+				out.Print("Showing all environments")
+			} else {
+				out.Printf("Showing environment %s", colors.Green(shown))
+			}
+		default:
+			out.Print(watchConsoleHelp)
+		}
+	}
+}
+
+// handleSignals will pause and resume watch when it receives the pause and resume
+// signals. The returned func stops handling them.
+func (console *watchConsole) handleSignals() func() {
+	if pauseSignal == nil {
+		return func() {}
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, pauseSignal, resumeSignal)
+	go func() {
+		for sig := range signals {
+			if sig == pauseSignal {
+				console.send(watchPause)
+			} else {
+				console.send(watchResume)
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(signals)
+	}
+}
+
+// output will return a writer for an environment's log that only writes when the
+// environment is shown.
+func (console *watchConsole) output(name string, w io.Writer) io.Writer {
+	return &consoleWriter{console: console, name: name, w: w}
+}
+
+type consoleWriter struct {
+	console *watchConsole
+	name    string
+	w       io.Writer
+}
+
+func (writer *consoleWriter) Write(p []byte) (int, error) {
+	if !writer.console.isShown(writer.name) {
+		return len(p), nil
+	}
+	return writer.w.Write(p)
+}
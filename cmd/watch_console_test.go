@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchConsole(t *testing.T) {
+	console := newWatchConsole()
+	development := console.register("development")
+	production := console.register("production")
+	assert.Equal(t, development, console.register("development"))
+
+	var out bytes.Buffer
+	console.run(strings.NewReader("p\n\nn\nS\nn\nn\nr\nwhat\n"), log.New(&out, "", 0))
+
+	assert.Equal(t, []watchControl{watchPause, watchSync, watchResume}, drainControls(development))
+	assert.Equal(t, []watchControl{watchPause, watchResume}, drainControls(production))
+	assert.Contains(t, out.String(), "Showing environment development")
+	assert.Contains(t, out.String(), "Showing environment production")
+	assert.Contains(t, out.String(), "Showing all environments")
+	assert.Contains(t, out.String(), "Commands:")
+
+	// every key is a command without waiting for enter
+	console.run(strings.NewReader("ps"), log.New(&out, "", 0))
+	assert.Equal(t, []watchControl{watchPause, watchSync}, drainControls(development))
+}
+
+func TestWatchConsoleOutput(t *testing.T) {
+	console := newWatchConsole()
+	console.register("development")
+	console.register("production")
+
+	var out bytes.Buffer
+	development := log.New(console.output("development", &out), "", 0)
+	production := log.New(console.output("production", &out), "", 0)
+
+	development.Print("one")
+	production.Print("two")
+	console.next()
+	development.Print("three")
+	production.Print("four")
+	assert.Equal(t, "one\ntwo\nthree\n", out.String())
+}
+
+func TestWatchConsoleSignals(t *testing.T) {
+	if pauseSignal == nil {
+		t.Skip("signals are not supported")
+	}
+	console := newWatchConsole()
+	controls := console.register("development")
+	stop := console.handleSignals()
+	defer stop()
+
+	process, _ := os.FindProcess(os.Getpid())
+	process.Signal(pauseSignal)
+	select {
+	case control := <-controls:
+		assert.Equal(t, watchPause, control)
+	case <-time.After(time.Second):
+		t.Error("pause signal was not handled")
+	}
+}
+
+func drainControls(controls chan watchControl) []watchControl {
+	received := []watchControl{}
+	for len(controls) > 0 {
+		received = append(received, <-controls)
+	}
+	return received
+}
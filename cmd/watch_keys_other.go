@@ -0,0 +1,12 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package cmd
+
+import "os"
+
+// keystrokeMode is not supported on this platform so commands are read a line
+// at a time.
+func keystrokeMode(f *os.File) func() {
+	return func() {}
+}
@@ -0,0 +1,29 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// keystrokeMode will switch the terminal of f to read every key as it is pressed
+// without echoing it. Signals like ctrl-c still work. The returned func restores
+// the terminal, nothing is changed if f is not a terminal.
+func keystrokeMode(f *os.File) func() {
+	fd := int(f.Fd())
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return func() {}
+	}
+	original := *termios
+	termios.Lflag &^= unix.ICANON | unix.ECHO
+	termios.Cc[unix.VMIN] = 1
+	termios.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, termios); err != nil {
+		return func() {}
+	}
+	return func() { unix.IoctlSetTermios(fd, ioctlSetTermios, &original) }
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// keystrokeMode will switch the console of f to read every key as it is pressed
+// without echoing it. Ctrl-c still works. The returned func restores the console,
+// nothing is changed if f is not a console.
+func keystrokeMode(f *os.File) func() {
+	handle := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return func() {}
+	}
+	if err := windows.SetConsoleMode(handle, mode&^(windows.ENABLE_LINE_INPUT|windows.ENABLE_ECHO_INPUT)); err != nil {
+		return func() {}
+	}
+	return func() { windows.SetConsoleMode(handle, mode) }
+}
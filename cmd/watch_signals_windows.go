@@ -0,0 +1,9 @@
+package cmd
+
+import "os"
+
+// windows has no user signals so watch can only be paused interactively
+var (
+	pauseSignal  os.Signal
+	resumeSignal os.Signal
+)
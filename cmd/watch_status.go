@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/ratelimiter"
+)
+
+// watchStatus keeps the state of a running watch so that editors and other tools
+// can tell if the theme is in sync. It is served over http and written to a file.
+// All of its methods are safe to call on a nil status.
+type watchStatus struct {
+	mu        sync.Mutex
+	writeMu   sync.Mutex
+	path      string
+	server    *http.Server
+	running   bool
+	pid       int
+	startedAt time.Time
+	envs      map[string]*envStatus
+}
+
+type envStatus struct {
+	Env        string             `json:"env"`
+	ThemeID    string             `json:"theme_id"`
+	Store      string             `json:"store"`
+	InSync     bool               `json:"in_sync"`
+	Paused     bool               `json:"paused"`
+	LastSync   *time.Time         `json:"last_sync,omitempty"`
+	Queued     int                `json:"queued"`
+	Pending    int                `json:"pending"`
+	Held       int                `json:"held"`
+	ErrorCount int                `json:"error_count"`
+	Errors     map[string]string  `json:"errors"`
+	RateLimit  *ratelimiter.State `json:"rate_limit,omitempty"`
+}
+
+type watchStatusReport struct {
+	Running      bool         `json:"running"`
+	PID          int          `json:"pid"`
+	StartedAt    time.Time    `json:"started_at"`
+	Environments []*envStatus `json:"environments"`
+}
+
+// newWatchStatus will create a status that is written to path, if path is empty
+// the status is only kept in memory.
+func newWatchStatus(path string) *watchStatus {
+	return &watchStatus{
+		path:      path,
+		running:   true,
+		pid:       os.Getpid(),
+		startedAt: time.Now(),
+		envs:      map[string]*envStatus{},
+	}
+}
+
+// listen will serve the status as json on addr
+func (status *watchStatus) listen(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("could not start status server: %s", err)
+	}
+	status.server = &http.Server{Handler: status.handler()}
+	go status.server.Serve(listener)
+	return nil
+}
+
+func (status *watchStatus) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Write(status.report())
+	})
+	return mux
+}
+
+// close will mark watch as stopped and stop serving the status
+func (status *watchStatus) close() {
+	if status == nil {
+		return
+	}
+	status.update(func() { status.running = false })
+	if status.server != nil {
+		status.server.Close()
+	}
+}
+
+// started will add an environment to the status when its watch starts
+func (status *watchStatus) started(ctx *cmdutil.Ctx) {
+	if status == nil {
+		return
+	}
+	status.update(func() {
+		status.envs[ctx.Env.Name] = &envStatus{
+			Env:     ctx.Env.Name,
+			ThemeID: ctx.Env.ThemeID,
+			Store:   ctx.Env.Domain,
+			InSync:  true,
+			Errors:  map[string]string{},
+		}
+	})
+}
+
+// succeeded will record that a change to path was synced
+func (status *watchStatus) succeeded(ctx *cmdutil.Ctx, path string) {
+	status.updateEnv(ctx, func(envStat *envStatus) {
+		now := time.Now()
+		envStat.LastSync = &now
+		delete(envStat.Errors, path)
+	})
+}
+
+// failed will record the error of the last change to path
+func (status *watchStatus) failed(ctx *cmdutil.Ctx, path string, err error) {
+	status.updateEnv(ctx, func(envStat *envStatus) {
+		envStat.Errors[path] = err.Error()
+	})
+}
+
+// queues will record how many changes are waiting to be uploaded
+func (status *watchStatus) queues(ctx *cmdutil.Ctx, paused bool, queued, pending, held int) {
+	status.updateEnv(ctx, func(envStat *envStatus) {
+		envStat.Paused = paused
+		envStat.Queued = queued
+		envStat.Pending = pending
+		envStat.Held = held
+		envStat.ErrorCount = len(ctx.Errors())
+		envStat.InSync = queued+pending+held == 0
+	})
+}
+
+func (status *watchStatus) updateEnv(ctx *cmdutil.Ctx, fn func(*envStatus)) {
+	if status == nil {
+		return
+	}
+	status.update(func() {
+		if envStat, ok := status.envs[ctx.Env.Name]; ok {
+			fn(envStat)
+		}
+	})
+}
+
+// update will change the status and write it to the status file
+func (status *watchStatus) update(fn func()) {
+	status.mu.Lock()
+	fn()
+	status.mu.Unlock()
+
+	if status.path == "" {
+		return
+	}
+	// the file is renamed into place so that readers never see a partial status,
+	// writes are serialized so that environments do not share the tmp file at once
+	status.writeMu.Lock()
+	defer status.writeMu.Unlock()
+	tmp := status.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, status.report(), 0644); err == nil {
+		os.Rename(tmp, status.path)
+	}
+}
+
+// report will return the status as json
+func (status *watchStatus) report() []byte {
+	status.mu.Lock()
+	defer status.mu.Unlock()
+
+	report := watchStatusReport{
+		Running:      status.running,
+		PID:          status.pid,
+		StartedAt:    status.startedAt,
+		Environments: []*envStatus{},
+	}
+	names := []string{}
+	for name := range status.envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		envStat := *status.envs[name]
+		if limiter, ok := ratelimiter.Lookup(envStat.Store); ok {
+			state := limiter.State()
+			envStat.RateLimit = &state
+		}
+		report.Environments = append(report.Environments, &envStat)
+	}
+
+	data, _ := json.MarshalIndent(report, "", "  ")
+	return append(data, '\n')
+}
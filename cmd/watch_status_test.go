@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Shopify/themekit/src/file"
+)
+
+func readWatchStatus(t *testing.T, data []byte) watchStatusReport {
+	report := watchStatusReport{}
+	assert.Nil(t, json.Unmarshal(data, &report))
+	return report
+}
+
+func TestWatchStatus(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "status")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "status.json")
+
+	ctx, _, _, _, _ := createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.ThemeID = "123"
+	ctx.Env.Domain = "status.myshopify.com"
+
+	status := newWatchStatus(path)
+	status.started(ctx)
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	report := readWatchStatus(t, data)
+	assert.True(t, report.Running)
+	assert.Equal(t, os.Getpid(), report.PID)
+	if assert.Equal(t, 1, len(report.Environments)) {
+		assert.Equal(t, "development", report.Environments[0].Env)
+		assert.Equal(t, "123", report.Environments[0].ThemeID)
+		assert.Equal(t, "status.myshopify.com", report.Environments[0].Store)
+		assert.True(t, report.Environments[0].InSync)
+		assert.Nil(t, report.Environments[0].LastSync)
+	}
+
+	ctx.Err("upload failed")
+	status.failed(ctx, "assets/app.js", fmt.Errorf("upload failed"))
+	status.queues(ctx, true, 2, 1, 0)
+	report = readWatchStatus(t, status.report())
+	envStat := report.Environments[0]
+	assert.Equal(t, map[string]string{"assets/app.js": "upload failed"}, envStat.Errors)
+	assert.Equal(t, 1, envStat.ErrorCount)
+	assert.True(t, envStat.Paused)
+	assert.Equal(t, 2, envStat.Queued)
+	assert.Equal(t, 1, envStat.Pending)
+	assert.False(t, envStat.InSync)
+
+	status.succeeded(ctx, "assets/app.js")
+	status.queues(ctx, false, 0, 0, 0)
+	report = readWatchStatus(t, status.report())
+	envStat = report.Environments[0]
+	assert.Equal(t, map[string]string{}, envStat.Errors)
+	assert.NotNil(t, envStat.LastSync)
+	assert.True(t, envStat.InSync)
+
+	status.close()
+	data, _ = ioutil.ReadFile(path)
+	assert.False(t, readWatchStatus(t, data).Running)
+
+	var nilStatus *watchStatus
+	nilStatus.started(ctx)
+	nilStatus.failed(ctx, "assets/app.js", fmt.Errorf("upload failed"))
+	nilStatus.close()
+}
+
+func TestWatchStatusConcurrentWrites(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "status")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "status.json")
+
+	status := newWatchStatus(path)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, _, _, _, _ := createTestCtx()
+			ctx.Env.Name = fmt.Sprintf("env%d", i)
+			status.started(ctx)
+			status.queues(ctx, false, i, 0, 0)
+		}(i)
+	}
+	wg.Wait()
+
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(readWatchStatus(t, data).Environments))
+}
+
+func TestWatchStatusHandler(t *testing.T) {
+	ctx, _, _, _, _ := createTestCtx()
+	ctx.Env.Name = "production"
+	status := newWatchStatus("")
+	status.started(ctx)
+
+	server := httptest.NewServer(status.handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/status")
+	if assert.Nil(t, err) {
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		report := readWatchStatus(t, data)
+		if assert.Equal(t, 1, len(report.Environments)) {
+			assert.Equal(t, "production", report.Environments[0].Env)
+		}
+	}
+
+	assert.Nil(t, status.listen("127.0.0.1:0"))
+	status.close()
+
+	err = newWatchStatus("").listen("not an address")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not start status server")
+	}
+}
+
+func TestWatchReportsStatus(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "status")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "assets"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "assets", "app.js"), []byte(""), 0644)
+
+	signalChan := make(chan os.Signal)
+	eventChan := make(chan file.Event)
+	ctx, client, _, _, _ := createTestCtx()
+	ctx.Env.Name = "development"
+	ctx.Env.Directory = dir
+	client.On("UpdateAsset", mock.Anything, "").Return(nil)
+	go func() {
+		eventChan <- file.Event{Op: file.Update, Path: "assets/app.js"}
+		signalChan <- os.Interrupt
+	}()
+
+	status := newWatchStatus("")
+	assert.Nil(t, watch(ctx, eventChan, signalChan, nil, &noopNotify{}, status))
+	report := readWatchStatus(t, status.report())
+	if assert.Equal(t, 1, len(report.Environments)) {
+		assert.NotNil(t, report.Environments[0].LastSync)
+		assert.True(t, report.Environments[0].InSync)
+	}
+}
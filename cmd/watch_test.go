@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,14 +18,14 @@ import (
 
 type testAdapter struct{ mock.Mock }
 
-func (adapter *testAdapter) notify(ctx *cmdutil.Ctx, path string) {
-	adapter.Called(ctx, path)
+func (adapter *testAdapter) notify(ctx *cmdutil.Ctx, event file.Event) {
+	adapter.Called(ctx, event)
 }
 
 func TestWatch(t *testing.T) {
 	ctx, _, _, _, _ := createTestCtx()
 	ctx.Env.ReadOnly = true
-	err := watch(ctx, make(chan file.Event), make(chan os.Signal), nil)
+	err := watch(ctx, make(chan file.Event), make(chan os.Signal), nil, nil, nil)
 	if assert.NotNil(t, err) {
 		assert.Contains(t, err.Error(), "environment is reaonly")
 	}
@@ -31,7 +34,7 @@ func TestWatch(t *testing.T) {
 	ctx, _, _, stdOut, _ := createTestCtx()
 	ctx.Flags.ConfigPath = "config.yml"
 	eventChan <- file.Event{Path: ctx.Flags.ConfigPath}
-	err = watch(ctx, eventChan, make(chan os.Signal), nil)
+	err = watch(ctx, eventChan, make(chan os.Signal), nil, nil, nil)
 	if assert.NotNil(t, err) {
 		assert.Contains(t, err.Error(), "reload")
 	}
@@ -47,13 +50,12 @@ func TestWatch(t *testing.T) {
 		signalChan <- os.Interrupt
 	}()
 	notifier := new(testAdapter)
-	notifier.On("notify", ctx, "assets/app.js")
-	err = watch(ctx, eventChan, signalChan, notifier)
+	err = watch(ctx, eventChan, signalChan, nil, notifier, nil)
 	assert.Nil(t, err)
 	assert.Contains(t, stdOut.String(), "Watching for file changes")
 	assert.Contains(t, stdOut.String(), "processing assets/app.js")
 	assert.Contains(t, stdErr.String(), "error loading assets/app.js: readAsset: ")
-	notifier.AssertExpectations(t)
+	notifier.AssertNotCalled(t, "notify", mock.Anything, mock.Anything)
 
 	signalChan = make(chan os.Signal)
 	eventChan = make(chan file.Event)
@@ -66,8 +68,8 @@ func TestWatch(t *testing.T) {
 		signalChan <- os.Interrupt
 	}()
 	notifier = new(testAdapter)
-	notifier.On("notify", ctx, "assets/app.js")
-	err = watch(ctx, eventChan, signalChan, notifier)
+	notifier.On("notify", ctx, file.Event{Op: file.Update, Path: "assets/app.js"})
+	err = watch(ctx, eventChan, signalChan, nil, notifier, nil)
 	assert.Nil(t, err)
 	assert.Contains(t, stdOut.String(), "Watching for file changes")
 	assert.Contains(t, stdOut.String(), "processing assets/app.js")
@@ -85,8 +87,8 @@ func TestWatch(t *testing.T) {
 		signalChan <- os.Interrupt
 	}()
 	notifier = new(testAdapter)
-	notifier.On("notify", ctx, "assets/app.js")
-	err = watch(ctx, eventChan, signalChan, notifier)
+	notifier.On("notify", ctx, file.Event{Op: file.Remove, Path: "assets/app.js"})
+	err = watch(ctx, eventChan, signalChan, nil, notifier, nil)
 	assert.Nil(t, err)
 	assert.Contains(t, stdOut.String(), "Watching for file changes")
 	assert.Contains(t, stdOut.String(), "processing assets/app.js")
@@ -105,8 +107,8 @@ func TestWatch(t *testing.T) {
 		eventChan <- file.Event{Op: file.Remove, Path: "assets/app.js"}
 	}()
 	notifier = new(testAdapter)
-	notifier.On("notify", ctx, "assets/app.js")
-	err = watch(ctx, eventChan, signalChan, notifier)
+	notifier.On("notify", ctx, file.Event{Op: file.Update, Path: "assets/app.js"})
+	err = watch(ctx, eventChan, signalChan, nil, notifier, nil)
 	assert.Nil(t, err)
 	assert.Contains(t, stdOut.String(), "Watching for file changes")
 	assert.Contains(t, stdOut.String(), "processing assets/app.js")
@@ -115,12 +117,65 @@ func TestWatch(t *testing.T) {
 	notifier.AssertExpectations(t)
 }
 
+func TestWatchMoveCriticalFile(t *testing.T) {
+	signalChan := make(chan os.Signal)
+	eventChan := make(chan file.Event)
+	ctx, client, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = "_testdata/projectdir"
+	client.On("UpdateAsset", shopify.Asset{Key: "assets/app.js", Checksum: "d41d8cd98f00b204e9800998ecf8427e"}, "").Return(nil)
+	client.On("DeleteAsset", shopify.Asset{Key: "layout/theme.liquid"}).Return(shopify.ErrCriticalFile)
+	go func() {
+		eventChan <- file.Event{Op: file.Update, Path: "assets/app.js"}
+		eventChan <- file.Event{Op: file.Remove, Path: "layout/theme.liquid"}
+		eventChan <- file.Event{Op: file.Skip, Path: "assets/app.js"}
+		signalChan <- os.Interrupt
+	}()
+	assert.Nil(t, watch(ctx, eventChan, signalChan, make(chan watchControl), &noopNotify{}, nil))
+	assert.Contains(t, stdOut.String(), "Updated assets/app.js")
+	assert.Contains(t, stdOut.String(), "layout/theme.liquid was kept on shopify because it is required by the theme")
+	client.AssertExpectations(t)
+
+	_, err := os.Stat(filepath.Join(ctx.Env.Directory, themekitDir))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWatchMoveFailedUpload(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "watchmove")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "snippets"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "snippets", "new.liquid"), []byte("{% if %}"), 0644)
+
+	signalChan := make(chan os.Signal)
+	eventChan := make(chan file.Event)
+	controls := make(chan watchControl)
+	ctx, client, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = dir
+	client.On("UpdateAsset", mock.MatchedBy(func(a shopify.Asset) bool { return a.Key == "snippets/new.liquid" }), "").Return(fmt.Errorf("Liquid syntax error")).Once()
+	client.On("UpdateAsset", mock.MatchedBy(func(a shopify.Asset) bool { return a.Key == "snippets/new.liquid" }), "").Return(nil)
+	client.On("DeleteAsset", shopify.Asset{Key: "snippets/old.liquid"}).Return(nil)
+	go func() {
+		eventChan <- file.Event{Op: file.Update, Path: "snippets/new.liquid"}
+		eventChan <- file.Event{Op: file.Remove, Path: "snippets/old.liquid", MovedTo: "snippets/new.liquid"}
+		controls <- watchPause
+		client.AssertNotCalled(t, "DeleteAsset", mock.Anything)
+		controls <- watchResume
+		eventChan <- file.Event{Op: file.Update, Path: "snippets/new.liquid"}
+		signalChan <- os.Interrupt
+	}()
+	assert.Nil(t, watch(ctx, eventChan, signalChan, controls, &noopNotify{}, nil))
+	assert.Contains(t, stdOut.String(), "snippets/old.liquid was kept on shopify until snippets/new.liquid is uploaded")
+	assert.Contains(t, stdOut.String(), "Updated snippets/new.liquid")
+	assert.Contains(t, stdOut.String(), "Deleted snippets/old.liquid")
+	client.AssertNumberOfCalls(t, "DeleteAsset", 1)
+}
+
 func TestPerform(t *testing.T) {
 	key := "assets/app.js"
 
 	ctx, m, _, _, se := createTestCtx()
 	perform(ctx, "bad", file.Update, "")
 	assert.Contains(t, se.String(), "readAsset: ")
+	assert.Equal(t, 0, len(ctx.Failures()))
 	m.AssertExpectations(t)
 
 	ctx, m, _, _, se = createTestCtx()
@@ -161,3 +216,224 @@ func TestPerform(t *testing.T) {
 
 	m.AssertExpectations(t)
 }
+
+type testChecksumStore map[string]string
+
+func (store testChecksumStore) Checksum(path string) string { return store[path] }
+
+func (store testChecksumStore) SetChecksum(path, checksum string) { store[path] = checksum }
+
+func TestPullRemoteChanges(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "twoway")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "snippets"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "snippets", "synced.liquid"), []byte("synced"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "snippets", "edited.liquid"), []byte("local edit"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "snippets", "same.liquid"), []byte("same"), 0644)
+
+	synced, _ := file.FileChecksum(dir, "snippets/synced.liquid")
+	store := testChecksumStore{
+		"snippets/synced.liquid": synced,
+		"snippets/edited.liquid": "old",
+		"snippets/same.liquid":   "old",
+	}
+	remote := newRemoteChecksums(map[string]string{
+		"snippets/synced.liquid":    "1",
+		"snippets/edited.liquid":    "1",
+		"snippets/same.liquid":      "1",
+		"snippets/unchanged.liquid": "1",
+	})
+
+	ctx, client, _, stdOut, stdErr := createTestCtx()
+	ctx.Env.Directory = dir
+	client.On("GetAllAssets").Return([]shopify.Asset{
+		{Key: "snippets/synced.liquid", Checksum: "2"},
+		{Key: "snippets/edited.liquid", Checksum: "2"},
+		{Key: "snippets/same.liquid", Checksum: "2"},
+		{Key: "snippets/unchanged.liquid", Checksum: "1"},
+		{Key: "snippets/new.liquid", Checksum: "2"},
+		{Key: "snippets/broken.liquid", Checksum: "2"},
+	}, nil)
+	client.On("GetAsset", "snippets/synced.liquid").Return(shopify.Asset{Key: "snippets/synced.liquid", Value: "remote edit"}, nil)
+	client.On("GetAsset", "snippets/edited.liquid").Return(shopify.Asset{Key: "snippets/edited.liquid", Value: "remote edit"}, nil)
+	client.On("GetAsset", "snippets/same.liquid").Return(shopify.Asset{Key: "snippets/same.liquid", Value: "same"}, nil)
+	client.On("GetAsset", "snippets/new.liquid").Return(shopify.Asset{Key: "snippets/new.liquid", Value: "new"}, nil)
+	client.On("GetAsset", "snippets/broken.liquid").Return(shopify.Asset{}, fmt.Errorf("server error"))
+
+	pullRemoteChanges(ctx, store, remote)
+	client.AssertNotCalled(t, "GetAsset", "snippets/unchanged.liquid")
+
+	read := func(name string) string {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, "snippets", name))
+		return string(data)
+	}
+	assert.Equal(t, "remote edit", read("synced.liquid"))
+	assert.Equal(t, "new", read("new.liquid"))
+	assert.Equal(t, "local edit", read("edited.liquid"))
+	assert.Equal(t, "remote edit", read("edited.liquid.remote"))
+	assert.Contains(t, stdOut.String(), "Downloaded remote changes to snippets/synced.liquid")
+	assert.Contains(t, stdErr.String(), "Conflict snippets/edited.liquid was changed locally and on shopify")
+	assert.Contains(t, stdErr.String(), "error downloading snippets/broken.liquid: server error")
+
+	synced, _ = file.FileChecksum(dir, "snippets/synced.liquid")
+	assert.Equal(t, synced, store["snippets/synced.liquid"])
+	assert.Equal(t, "old", store["snippets/edited.liquid"])
+	assert.Equal(t, "old", store["snippets/same.liquid"])
+	assert.Equal(t, map[string]string{
+		"snippets/synced.liquid":    "2",
+		"snippets/edited.liquid":    "2",
+		"snippets/same.liquid":      "2",
+		"snippets/unchanged.liquid": "1",
+		"snippets/new.liquid":       "2",
+	}, remote.checksums)
+
+	ctx, client, _, _, stdErr = createTestCtx()
+	client.On("GetAllAssets").Return([]shopify.Asset{}, fmt.Errorf("rate limited"))
+	pullRemoteChanges(ctx, store, remote)
+	assert.Contains(t, stdErr.String(), "error checking for remote changes: rate limited")
+}
+
+func TestPullRemoteJSONChanges(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "twoway")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "templates"), 0755)
+	pretty := "{\n  \"sections\": {\n    \"main\": {\n      \"type\": \"main\"\n    }\n  }\n}"
+	ioutil.WriteFile(filepath.Join(dir, "templates", "index.json"), []byte(pretty), 0644)
+
+	// the store is seeded with the checksum from shopify of the compacted json
+	synced := shopify.NewAsset("templates/index.json", []byte(`{"sections":{"main":{"type":"main"}}}`)).Checksum
+	store := testChecksumStore{"templates/index.json": synced}
+	remote := newRemoteChecksums(map[string]string{"templates/index.json": synced})
+
+	ctx, client, _, stdOut, stdErr := createTestCtx()
+	ctx.Env.Directory = dir
+	client.On("GetAllAssets").Return([]shopify.Asset{{Key: "templates/index.json", Checksum: "2"}}, nil)
+	client.On("GetAsset", "templates/index.json").Return(shopify.Asset{Key: "templates/index.json", Value: `{"sections":{"main":{"type":"featured"}}}`}, nil)
+
+	pullRemoteChanges(ctx, store, remote)
+
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "templates", "index.json"))
+	assert.Contains(t, string(data), `"type": "featured"`)
+	assert.NoFileExists(t, filepath.Join(dir, "templates", "index.json.remote"))
+	assert.Contains(t, stdOut.String(), "Downloaded remote changes to templates/index.json")
+	assert.NotContains(t, stdErr.String(), "Conflict")
+	assert.Equal(t, shopify.NewAsset("templates/index.json", data).Checksum, store["templates/index.json"])
+
+	// a pretty printed file that matches the compacted json on shopify is left as
+	// it is because it is the change that watch uploaded
+	ioutil.WriteFile(filepath.Join(dir, "templates", "index.json"), []byte(pretty), 0644)
+	ctx, client, _, stdOut, _ = createTestCtx()
+	ctx.Env.Directory = dir
+	client.On("GetAllAssets").Return([]shopify.Asset{{Key: "templates/index.json", Checksum: "3"}}, nil)
+	client.On("GetAsset", "templates/index.json").Return(shopify.Asset{Key: "templates/index.json", Value: `{"sections":{"main":{"type":"main"}}}`, Checksum: synced}, nil)
+	pullRemoteChanges(ctx, store, remote)
+	data, _ = ioutil.ReadFile(filepath.Join(dir, "templates", "index.json"))
+	assert.Equal(t, pretty, string(data))
+	assert.NotContains(t, stdOut.String(), "Downloaded remote changes")
+}
+
+func TestRemoteChecksumsNotify(t *testing.T) {
+	ctx, client, _, _, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	remote := newRemoteChecksums(map[string]string{"assets/app.js": "1", "assets/old.js": "1"})
+
+	remote.notify(ctx, file.Event{Path: "assets/app.js", Op: file.Update})
+	remote.notify(ctx, file.Event{Path: "assets/old.js", Op: file.Remove})
+	assert.Equal(t, map[string]string{"assets/app.js": "d41d8cd98f00b204e9800998ecf8427e"}, remote.checksums)
+
+	// an upload by watch is not downloaded again on the next poll
+	client.On("GetAllAssets").Return([]shopify.Asset{{Key: "assets/app.js", Checksum: "d41d8cd98f00b204e9800998ecf8427e"}}, nil)
+	pullRemoteChanges(ctx, testChecksumStore{}, remote)
+	client.AssertNotCalled(t, "GetAsset", mock.Anything)
+}
+
+func TestSyncLocalChanges(t *testing.T) {
+	remote := []shopify.Asset{{Key: "assets/logo.png"}, {Key: "assets/app.js", Checksum: "d41d8cd98f00b204e9800998ecf8427e"}}
+
+	ctx, client, _, stdOut, _ := createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	ctx.Flags.SyncOnStart = true
+	client.On("GetAllAssets").Return(remote, nil)
+	client.On("UpdateAsset", mock.MatchedBy(func(a shopify.Asset) bool { return a.Key == "config/settings_data.json" }), "").Return(nil)
+	assert.Nil(t, syncLocalChanges(ctx))
+	assert.Contains(t, stdOut.String(), "syncing 1 local changes to theme")
+	assert.Contains(t, stdOut.String(), "Updated config/settings_data.json")
+	assert.NotContains(t, stdOut.String(), "Skipped assets/app.js")
+	client.AssertNotCalled(t, "DeleteAsset", mock.Anything)
+
+	ctx, client, _, stdOut, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	ctx.Flags.SyncOnStart = true
+	ctx.Flags.Delete = true
+	client.On("GetAllAssets").Return(remote, nil)
+	client.On("UpdateAsset", mock.Anything, "").Return(nil)
+	client.On("DeleteAsset", shopify.Asset{Key: "assets/logo.png"}).Return(nil)
+	assert.Nil(t, syncLocalChanges(ctx))
+	assert.Contains(t, stdOut.String(), "syncing 2 local changes to theme")
+	assert.Contains(t, stdOut.String(), "Deleted assets/logo.png")
+
+	ctx, client, _, stdOut, _ = createTestCtx()
+	ctx.Env.Directory = filepath.Join("_testdata", "projectdir")
+	ctx.Args = []string{"assets/app.js"}
+	client.On("GetAllAssets").Return(remote, nil)
+	assert.Nil(t, syncLocalChanges(ctx))
+	assert.Contains(t, stdOut.String(), "local files are in sync with theme")
+	client.AssertNotCalled(t, "UpdateAsset", mock.Anything, mock.Anything)
+
+	ctx, client, _, _, _ = createTestCtx()
+	ctx.Env.ReadOnly = true
+	err := syncLocalChanges(ctx)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "environment is readonly")
+	}
+	client.AssertNotCalled(t, "GetAllAssets")
+}
+
+func TestWatchControls(t *testing.T) {
+	signalChan := make(chan os.Signal)
+	eventChan := make(chan file.Event)
+	controls := make(chan watchControl)
+	ctx, client, _, stdOut, _ := createTestCtx()
+	ctx.Flags.ConfigPath = "config.yml"
+	ctx.Env.Directory = "_testdata/projectdir"
+	client.On("DeleteAsset", shopify.Asset{Key: "assets/old.js"}).Return(fmt.Errorf("server error")).Once()
+	client.On("DeleteAsset", shopify.Asset{Key: "assets/old.js"}).Return(nil)
+	client.On("UpdateAsset", shopify.Asset{Key: "assets/app.js", Checksum: "d41d8cd98f00b204e9800998ecf8427e"}, "").Return(nil).Once()
+	notifier := new(testAdapter)
+	notifier.On("notify", ctx, mock.Anything)
+	go func() {
+		controls <- watchRetry
+		controls <- watchErrors
+		controls <- watchPause
+		eventChan <- file.Event{Op: file.Update, Path: "assets/app.js"}
+		eventChan <- file.Event{Op: file.Update, Path: "assets/old.js"}
+		eventChan <- file.Event{Op: file.Remove, Path: "assets/old.js"}
+		eventChan <- file.Event{Op: file.Update, Path: "assets/app.js"}
+		controls <- watchResume
+		controls <- watchErrors
+		controls <- watchRetry
+		controls <- watchRetry
+		signalChan <- os.Interrupt
+	}()
+	assert.Nil(t, watch(ctx, eventChan, signalChan, controls, notifier, nil))
+	output := stdOut.String()
+	assert.Contains(t, output, "there are no failed files to retry")
+	assert.Contains(t, output, "no errors")
+	assert.Contains(t, output, "Paused, changes will be queued until watch is resumed")
+	assert.Contains(t, output, "paused, queued assets/old.js")
+	assert.Contains(t, output, "Resumed, processing 2 queued changes")
+	assert.Contains(t, output, "Recent errors:")
+	assert.Contains(t, output, "(assets/old.js) server error")
+	assert.Contains(t, output, "Deleted assets/old.js")
+	assert.Equal(t, 2, strings.Count(output, "there are no failed files to retry"))
+	client.AssertNumberOfCalls(t, "UpdateAsset", 1)
+	client.AssertNumberOfCalls(t, "DeleteAsset", 2)
+}
+
+func TestQueueEvent(t *testing.T) {
+	queued := []file.Event{}
+	queued = queueEvent(queued, file.Event{Op: file.Update, Path: "assets/app.js"})
+	queued = queueEvent(queued, file.Event{Op: file.Update, Path: "assets/app.css"})
+	queued = queueEvent(queued, file.Event{Op: file.Remove, Path: "assets/app.js"})
+	assert.Equal(t, []file.Event{{Op: file.Update, Path: "assets/app.css"}, {Op: file.Remove, Path: "assets/app.js"}}, queued)
+}
@@ -17,6 +17,7 @@ require (
 	github.com/stretchr/testify v1.5.1
 	github.com/vbauerster/mpb v3.3.2+incompatible
 	golang.org/x/sync v0.1.0
+	golang.org/x/sys v0.13.0
 	golang.org/x/time v0.0.0-20200630173020-3af7569d3a1e
 	gopkg.in/yaml.v1 v1.0.0-20140924161607-9f9df34309c0
 )
@@ -32,7 +33,6 @@ require (
 	github.com/stretchr/objx v0.1.1 // indirect
 	golang.org/x/crypto v0.14.0 // indirect
 	golang.org/x/net v0.17.0 // indirect
-	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/term v0.13.0 // indirect
 	gopkg.in/yaml.v2 v2.2.2 // indirect
 )
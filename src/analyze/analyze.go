@@ -0,0 +1,240 @@
+// Package analyze builds a dependency graph of a theme from the references found
+// in its liquid, JSON and stylesheet files. The graph can then be used to find
+// references to files that do not exist and files that nothing references.
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+var (
+	tagRegex       = regexp.MustCompile(`(?m)(?:{%-?|^)\s*(render|include|section|sections|layout)\s+['"]([^'"]+)['"]`)
+	assetURLRegex  = regexp.MustCompile(`['"]([^'"]+)['"]\s*\|\s*(?:asset_url|asset_img_url)`)
+	cssURLRegex    = regexp.MustCompile(`url\(\s*['"]?([^'")?#]+)`)
+	schemaRegex    = regexp.MustCompile(`{%-?\s*schema\s*-?%}([\s\S]*?){%-?\s*endschema\s*-?%}`)
+	unusedFolders  = []string{"assets/", "snippets/", "sections/"}
+	jsonRefFolders = []string{"config/", "sections/", "templates/"}
+)
+
+// Reference is a single link from one file in the theme to another
+type Reference struct {
+	From string
+	To   string
+}
+
+// Graph describes which files in a theme reference each other
+type Graph struct {
+	files      map[string]bool
+	addable    map[string]bool
+	references map[string]map[string]bool
+}
+
+// New will build a dependency graph from the assets of a theme
+func New(assets []shopify.Asset) *Graph {
+	graph := &Graph{
+		files:      map[string]bool{},
+		addable:    map[string]bool{},
+		references: map[string]map[string]bool{},
+	}
+	for _, asset := range assets {
+		graph.files[asset.Key] = true
+	}
+	for _, asset := range assets {
+		if asset.Value != "" {
+			graph.parse(asset)
+		}
+	}
+	return graph
+}
+
+func (graph *Graph) parse(asset shopify.Asset) {
+	switch ext := path.Ext(asset.Key); {
+	case ext == ".liquid":
+		for _, match := range tagRegex.FindAllStringSubmatch(asset.Value, -1) {
+			graph.addTagReference(asset.Key, match[1], match[2])
+		}
+		for _, match := range assetURLRegex.FindAllStringSubmatch(asset.Value, -1) {
+			graph.addAssetReference(asset.Key, match[1])
+		}
+		if schema := schemaRegex.FindStringSubmatch(asset.Value); schema != nil && strings.Contains(schema[1], `"presets"`) {
+			// sections with presets can be added to templates in the theme editor
+			graph.addable[asset.Key] = true
+		}
+		if strings.HasPrefix(asset.Key, "assets/") {
+			graph.parseStylesheet(asset)
+		}
+	case ext == ".css" || ext == ".scss":
+		graph.parseStylesheet(asset)
+	case ext == ".json" && hasPrefix(asset.Key, jsonRefFolders):
+		var data interface{}
+		if err := json.Unmarshal([]byte(asset.Value), &data); err == nil {
+			graph.parseJSON(asset.Key, data)
+		}
+	}
+}
+
+func (graph *Graph) parseStylesheet(asset shopify.Asset) {
+	for _, match := range cssURLRegex.FindAllStringSubmatch(asset.Value, -1) {
+		target := strings.TrimSpace(match[1])
+		if target == "" || strings.Contains(target, ":") || strings.HasPrefix(target, "/") || strings.Contains(target, "{{") {
+			continue
+		}
+		graph.addAssetReference(asset.Key, path.Base(target))
+	}
+}
+
+// parseJSON will find section types in JSON templates, section groups and
+// settings data as well as the layout used by JSON templates.
+func (graph *Graph) parseJSON(key string, data interface{}) {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		if layout, ok := value["layout"].(string); ok && strings.HasPrefix(key, "templates/") {
+			graph.addTagReference(key, "layout", layout)
+		}
+		if sections, ok := value["sections"].(map[string]interface{}); ok {
+			for _, section := range sections {
+				if sectionData, ok := section.(map[string]interface{}); ok {
+					if sectionType, ok := sectionData["type"].(string); ok && !strings.HasPrefix(sectionType, "@") {
+						graph.addReference(key, "sections/"+sectionType+".liquid")
+					}
+				}
+			}
+		}
+		for _, child := range value {
+			graph.parseJSON(key, child)
+		}
+	case []interface{}:
+		for _, child := range value {
+			graph.parseJSON(key, child)
+		}
+	}
+}
+
+func (graph *Graph) addTagReference(from, tag, name string) {
+	switch tag {
+	case "render", "include":
+		graph.addReference(from, "snippets/"+name+".liquid")
+	case "section":
+		graph.addReference(from, "sections/"+name+".liquid")
+	case "sections":
+		graph.addReference(from, "sections/"+name+".json")
+	case "layout":
+		graph.addReference(from, "layout/"+name+".liquid")
+	}
+}
+
+// addAssetReference will resolve an asset name to the liquid source of a
+// compiled asset if that is the file that exists in the project.
+func (graph *Graph) addAssetReference(from, name string) {
+	target := "assets/" + name
+	if !graph.files[target] && graph.files[target+".liquid"] {
+		target += ".liquid"
+	}
+	graph.addReference(from, target)
+}
+
+func (graph *Graph) addReference(from, to string) {
+	if from == to {
+		return
+	}
+	if _, ok := graph.references[from]; !ok {
+		graph.references[from] = map[string]bool{}
+	}
+	graph.references[from][to] = true
+}
+
+// References will return all of the references in the graph sorted by the file
+// they come from.
+func (graph *Graph) References() []Reference {
+	refs := []Reference{}
+	for _, from := range sortedKeys(graph.references) {
+		for _, to := range sortedKeys(graph.references[from]) {
+			refs = append(refs, Reference{From: from, To: to})
+		}
+	}
+	return refs
+}
+
+// Missing will return all references to files that do not exist. Any file that
+// is ignored is not reported because it is not managed by the project.
+func (graph *Graph) Missing(ignored func(string) bool) []Reference {
+	missing := []Reference{}
+	for _, ref := range graph.References() {
+		if !graph.files[ref.To] && !ignored(ref.To) {
+			missing = append(missing, ref)
+		}
+	}
+	return missing
+}
+
+// Unused will return all assets, snippets and sections that are not referenced
+// by any other file. Sections with presets are not reported since they can be
+// added through the theme editor.
+func (graph *Graph) Unused() []string {
+	referenced := map[string]bool{}
+	for _, targets := range graph.references {
+		for to := range targets {
+			referenced[to] = true
+		}
+	}
+
+	unused := []string{}
+	for _, key := range sortedKeys(graph.files) {
+		if hasPrefix(key, unusedFolders) && !referenced[key] && !graph.addable[key] {
+			unused = append(unused, key)
+		}
+	}
+	return unused
+}
+
+// WriteDot will write out the graph in the graphviz dot format. Missing files
+// are drawn in red and unused files in gray.
+func (graph *Graph) WriteDot(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "digraph theme {\n  rankdir=LR;\n  node [shape=box];"); err != nil {
+		return err
+	}
+	for _, key := range graph.Unused() {
+		fmt.Fprintf(w, "  %q [color=gray];\n", key)
+	}
+	for _, ref := range graph.References() {
+		if !graph.files[ref.To] {
+			fmt.Fprintf(w, "  %q [color=red];\n", ref.To)
+		}
+		fmt.Fprintf(w, "  %q -> %q;\n", ref.From, ref.To)
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+func hasPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(values interface{}) []string {
+	keys := []string{}
+	switch m := values.(type) {
+	case map[string]bool:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]map[string]bool:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,93 @@
+package analyze
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Shopify/themekit/src/shopify"
+)
+
+var testAssets = []shopify.Asset{
+	{Key: "layout/theme.liquid", Value: `{{ 'app.css' | asset_url | stylesheet_tag }}
+{% section 'header' %}
+{%- render "icon", size: 2 -%}
+{% sections 'footer-group' %}
+{{ content_for_layout }}`},
+	{Key: "layout/password.liquid", Value: `{% include 'gone' %}`},
+	{Key: "templates/index.json", Value: `{"layout": "password", "sections": {"main": {"type": "hero", "blocks": {"a": {"type": "@app"}}}}, "order": ["main"]}`},
+	{Key: "templates/product.liquid", Value: `{% liquid
+  render 'price'
+  layout 'theme'
+%}`},
+	{Key: "sections/header.liquid", Value: `<img src="{{ 'logo.png' | asset_url }}">`},
+	{Key: "sections/hero.liquid", Value: `hero`},
+	{Key: "sections/promo.liquid", Value: `{% schema %}{"name": "Promo", "presets": [{"name": "Promo"}]}{% endschema %}`},
+	{Key: "sections/old.liquid", Value: `{% schema %}{"name": "Old"}{% endschema %}`},
+	{Key: "sections/footer-group.json", Value: `{"type": "footer", "sections": {"f": {"type": "footer"}}}`},
+	{Key: "snippets/icon.liquid", Value: `icon`},
+	{Key: "snippets/price.liquid", Value: `price`},
+	{Key: "snippets/dead.liquid", Value: `dead`},
+	{Key: "assets/app.css.liquid", Value: `body { background: url('bg.png'); font: url("data:font/woff2;base64,xx"); }`},
+	{Key: "assets/bg.png", Attachment: "aGVsbG8="},
+	{Key: "assets/logo.png", Attachment: "aGVsbG8="},
+	{Key: "assets/unused.js", Value: `console.log('hi')`},
+	{Key: "config/settings_data.json", Value: `{"current": {"sections": {"header": {"type": "header"}}}}`},
+}
+
+func TestNew(t *testing.T) {
+	graph := New(testAssets)
+	assert.Equal(t, []Reference{
+		{From: "assets/app.css.liquid", To: "assets/bg.png"},
+		{From: "config/settings_data.json", To: "sections/header.liquid"},
+		{From: "layout/password.liquid", To: "snippets/gone.liquid"},
+		{From: "layout/theme.liquid", To: "assets/app.css.liquid"},
+		{From: "layout/theme.liquid", To: "sections/footer-group.json"},
+		{From: "layout/theme.liquid", To: "sections/header.liquid"},
+		{From: "layout/theme.liquid", To: "snippets/icon.liquid"},
+		{From: "sections/footer-group.json", To: "sections/footer.liquid"},
+		{From: "sections/header.liquid", To: "assets/logo.png"},
+		{From: "templates/index.json", To: "layout/password.liquid"},
+		{From: "templates/index.json", To: "sections/hero.liquid"},
+		{From: "templates/product.liquid", To: "layout/theme.liquid"},
+		{From: "templates/product.liquid", To: "snippets/price.liquid"},
+	}, graph.References())
+}
+
+func TestGraph_Missing(t *testing.T) {
+	graph := New(testAssets)
+	assert.Equal(t, []Reference{
+		{From: "layout/password.liquid", To: "snippets/gone.liquid"},
+		{From: "sections/footer-group.json", To: "sections/footer.liquid"},
+	}, graph.Missing(func(string) bool { return false }))
+
+	assert.Equal(t, []Reference{
+		{From: "sections/footer-group.json", To: "sections/footer.liquid"},
+	}, graph.Missing(func(key string) bool { return strings.HasPrefix(key, "snippets/") }))
+}
+
+func TestGraph_Unused(t *testing.T) {
+	graph := New(testAssets)
+	assert.Equal(t, []string{"assets/unused.js", "sections/old.liquid", "snippets/dead.liquid"}, graph.Unused())
+}
+
+func TestGraph_WriteDot(t *testing.T) {
+	graph := New([]shopify.Asset{
+		{Key: "layout/theme.liquid", Value: `{% render 'a' %}{% render 'b' %}`},
+		{Key: "snippets/a.liquid", Value: `a`},
+		{Key: "snippets/c.liquid", Value: `c`},
+	})
+	var buf bytes.Buffer
+	assert.Nil(t, graph.WriteDot(&buf))
+	assert.Equal(t, `digraph theme {
+  rankdir=LR;
+  node [shape=box];
+  "snippets/c.liquid" [color=gray];
+  "layout/theme.liquid" -> "snippets/a.liquid";
+  "snippets/b.liquid" [color=red];
+  "layout/theme.liquid" -> "snippets/b.liquid";
+}
+`, buf.String())
+}
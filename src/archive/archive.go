@@ -0,0 +1,205 @@
+// Package archive reads and writes the zip and tar.gz files that themes are
+// stored in. Files are written one at a time so that large themes do not have to
+// be held in memory.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Supported archive formats
+const (
+	Zip   = "zip"
+	TarGz = "tar.gz"
+)
+
+var (
+	// ErrUnknownFormat is returned when the archive is not a zip or tar.gz file
+	ErrUnknownFormat = errors.New("unknown archive format, only .zip and .tar.gz are supported")
+)
+
+// Writer adds files to an archive
+type Writer interface {
+	Add(name string, data []byte) error
+	Close() error
+}
+
+// Format will return the archive format of a path based on its extension or an
+// empty string if it is not an archive.
+func Format(path string) string {
+	switch lower := strings.ToLower(path); {
+	case strings.HasSuffix(lower, ".zip"):
+		return Zip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return TarGz
+	}
+	return ""
+}
+
+// Create will create a new archive at the path in the format defined by the
+// path's extension.
+func Create(path string) (Writer, error) {
+	format := Format(path)
+	if format == "" {
+		return nil, ErrUnknownFormat
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriter(file, format)
+}
+
+// NewWriter will create an archive writer that writes to w. If w is also a
+// closer, it is closed when the archive is closed.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case Zip:
+		return &zipWriter{dst: w, zip: zip.NewWriter(w)}, nil
+	case TarGz:
+		gz := gzip.NewWriter(w)
+		return &tarWriter{dst: w, gz: gz, tar: tar.NewWriter(gz)}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+type zipWriter struct {
+	dst io.Writer
+	zip *zip.Writer
+}
+
+func (w *zipWriter) Add(name string, data []byte) error {
+	f, err := w.zip.CreateHeader(&zip.FileHeader{
+		Name:     filepath.ToSlash(name),
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func (w *zipWriter) Close() error {
+	err := w.zip.Close()
+	return closeDst(w.dst, err)
+}
+
+type tarWriter struct {
+	dst io.Writer
+	gz  *gzip.Writer
+	tar *tar.Writer
+}
+
+func (w *tarWriter) Add(name string, data []byte) error {
+	if err := w.tar.WriteHeader(&tar.Header{
+		Name:    filepath.ToSlash(name),
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err := w.tar.Write(data)
+	return err
+}
+
+func (w *tarWriter) Close() error {
+	err := w.tar.Close()
+	if gzErr := w.gz.Close(); err == nil {
+		err = gzErr
+	}
+	return closeDst(w.dst, err)
+}
+
+func closeDst(dst io.Writer, err error) error {
+	if closer, ok := dst.(io.Closer); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Walk will call fn for every file in the archive at path, in the order that
+// they are stored. Directories are skipped.
+func Walk(path string, fn func(name string, data []byte) error) error {
+	switch Format(path) {
+	case Zip:
+		return walkZip(path, fn)
+	case TarGz:
+		return walkTarGz(path, fn)
+	}
+	return ErrUnknownFormat
+}
+
+func walkZip(path string, fn func(name string, data []byte) error) error {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, zipFile := range reader.File {
+		if zipFile.FileInfo().IsDir() {
+			continue
+		}
+		contents, err := zipFile.Open()
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadAll(contents)
+		contents.Close()
+		if err != nil {
+			return err
+		}
+		if err := fn(filepath.ToSlash(zipFile.Name), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkTarGz(path string, fn func(name string, data []byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !header.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if err := fn(filepath.ToSlash(header.Name), data); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, Zip, Format("backup.zip"))
+	assert.Equal(t, Zip, Format("BACKUP.ZIP"))
+	assert.Equal(t, TarGz, Format("backup.tar.gz"))
+	assert.Equal(t, TarGz, Format("backup.tgz"))
+	assert.Equal(t, "", Format("backup.tar"))
+	assert.Equal(t, "", Format("backups"))
+}
+
+func TestCreateAndWalk(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "archive")
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"theme.zip", "theme.tar.gz"} {
+		path := filepath.Join(dir, name)
+		writer, err := Create(path)
+		assert.Nil(t, err)
+		assert.Nil(t, writer.Add("assets/app.js", []byte("alert('hi')")))
+		assert.Nil(t, writer.Add(filepath.Join("layout", "theme.liquid"), []byte("{{ content_for_layout }}")))
+		assert.Nil(t, writer.Close())
+
+		files := map[string]string{}
+		order := []string{}
+		err = Walk(path, func(name string, data []byte) error {
+			files[name] = string(data)
+			order = append(order, name)
+			return nil
+		})
+		assert.Nil(t, err, name)
+		assert.Equal(t, []string{"assets/app.js", "layout/theme.liquid"}, order, name)
+		assert.Equal(t, "{{ content_for_layout }}", files["layout/theme.liquid"], name)
+
+		err = Walk(path, func(string, []byte) error { return fmt.Errorf("stop") })
+		assert.EqualError(t, err, "stop")
+	}
+
+	_, err := Create(filepath.Join(dir, "theme.rar"))
+	assert.Equal(t, ErrUnknownFormat, err)
+	_, err = Create(filepath.Join(dir, "nope", "theme.zip"))
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrUnknownFormat, Walk(filepath.Join(dir, "theme.rar"), nil))
+	assert.NotNil(t, Walk(filepath.Join(dir, "nope.zip"), nil))
+	assert.NotNil(t, Walk(filepath.Join(dir, "nope.tar.gz"), nil))
+
+	ioutil.WriteFile(filepath.Join(dir, "bad.tar.gz"), []byte("not gzip"), 0644)
+	assert.NotNil(t, Walk(filepath.Join(dir, "bad.tar.gz"), nil))
+}
+
+func TestNewWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf, Zip)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Add("a.txt", []byte("a")))
+	assert.Nil(t, writer.Close())
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("PK")))
+
+	_, err = NewWriter(&buf, "rar")
+	assert.Equal(t, ErrUnknownFormat, err)
+}
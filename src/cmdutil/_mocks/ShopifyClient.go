@@ -33,6 +33,27 @@ func (_m *ShopifyClient) CreateNewTheme(_a0 string) (shopify.Theme, error) {
 	return r0, r1
 }
 
+// CreateThemeFromSrc provides a mock function with given fields: _a0, _a1, _a2
+func (_m *ShopifyClient) CreateThemeFromSrc(_a0 string, _a1 string, _a2 string) (shopify.Theme, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 shopify.Theme
+	if rf, ok := ret.Get(0).(func(string, string, string) shopify.Theme); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Get(0).(shopify.Theme)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteAsset provides a mock function with given fields: _a0
 func (_m *ShopifyClient) DeleteAsset(_a0 shopify.Asset) error {
 	ret := _m.Called(_a0)
@@ -8,6 +8,7 @@ import (
 type shopifyClient interface {
 	GetShop() (shopify.Shop, error)
 	CreateNewTheme(string) (shopify.Theme, error)
+	CreateThemeFromSrc(string, string, string) (shopify.Theme, error)
 	GetInfo() (shopify.Theme, error)
 	PublishTheme() error
 	Themes() ([]shopify.Theme, error)
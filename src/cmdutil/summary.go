@@ -10,9 +10,10 @@ import (
 )
 
 type cmdSummary struct {
-	actions, downloaded, uploaded, skipped, removed int32
-	disabled                                        bool
-	errors                                          []string
+	actions, downloaded, uploaded, skipped, removed, resumed int32
+	disabled                                                 bool
+	errors                                                   []string
+	failed                                                   map[string]file.Op
 }
 
 func (sum *cmdSummary) completeOp(op file.Op) {
@@ -29,6 +30,10 @@ func (sum *cmdSummary) completeOp(op file.Op) {
 	}
 }
 
+func (sum *cmdSummary) resume(count int) {
+	atomic.AddInt32(&sum.resumed, int32(count))
+}
+
 func (sum *cmdSummary) disable() {
 	sum.disabled = true
 }
@@ -37,12 +42,19 @@ func (sum *cmdSummary) err(errStr string) {
 	sum.errors = append(sum.errors, errStr)
 }
 
+func (sum *cmdSummary) fail(path string, op file.Op) {
+	if sum.failed == nil {
+		sum.failed = map[string]file.Op{}
+	}
+	sum.failed[path] = op
+}
+
 func (sum *cmdSummary) hasErrors() bool {
 	return !sum.disabled && len(sum.errors) > 0
 }
 
 func (sum *cmdSummary) display(ctx *Ctx) {
-	if sum.disabled || sum.actions == 0 {
+	if sum.disabled || (sum.actions == 0 && sum.resumed == 0) {
 		return
 	}
 	var results = []string{fmt.Sprintf("%v files", sum.actions)}
@@ -58,6 +70,9 @@ func (sum *cmdSummary) display(ctx *Ctx) {
 	if sum.skipped > 0 {
 		results = append(results, fmt.Sprintf("%v: %v", colors.Cyan("No Change"), sum.skipped))
 	}
+	if sum.resumed > 0 {
+		results = append(results, fmt.Sprintf("%v: %v", colors.Cyan("Resumed"), sum.resumed))
+	}
 	if len(sum.errors) > 0 {
 		results = append(results, fmt.Sprintf("%v: %v", colors.Red("Errored"), len(sum.errors)))
 	}
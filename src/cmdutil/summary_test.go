@@ -30,6 +30,14 @@ func TestSummaryCompleteOp(t *testing.T) {
 	assert.Equal(t, summary.actions, int32(4))
 }
 
+func TestSummaryResume(t *testing.T) {
+	summary := cmdSummary{}
+	summary.resume(3)
+	summary.resume(2)
+	assert.Equal(t, summary.resumed, int32(5))
+	assert.Equal(t, summary.actions, int32(0))
+}
+
 func TestSummaryDisable(t *testing.T) {
 	summary := cmdSummary{}
 	assert.False(t, summary.disabled)
@@ -44,6 +52,14 @@ func TestSummaryErr(t *testing.T) {
 	assert.Equal(t, summary.errors, []string{"no good"})
 }
 
+func TestSummaryFail(t *testing.T) {
+	summary := cmdSummary{}
+	summary.fail("assets/app.js", file.Update)
+	summary.fail("snippets/old.liquid", file.Remove)
+	summary.fail("assets/app.js", file.Update)
+	assert.Equal(t, map[string]file.Op{"assets/app.js": file.Update, "snippets/old.liquid": file.Remove}, summary.failed)
+}
+
 func TestSummaryHasErrors(t *testing.T) {
 	summary := cmdSummary{}
 	assert.False(t, summary.hasErrors())
@@ -74,6 +90,14 @@ func TestSummaryDisplay(t *testing.T) {
 	assert.Equal(t, out, fmt.Sprintf("[sum] 23 files, No Change: 11\n"))
 	assert.Equal(t, err, "")
 
+	out, err = rundisplay(cmdSummary{actions: 23, uploaded: 23, resumed: 7})
+	assert.Equal(t, out, fmt.Sprintf("[sum] 23 files, Updated: 23, Resumed: 7\n"))
+	assert.Equal(t, err, "")
+
+	out, err = rundisplay(cmdSummary{resumed: 7})
+	assert.Equal(t, out, fmt.Sprintf("[sum] 0 files, Resumed: 7\n"))
+	assert.Equal(t, err, "")
+
 	out, err = rundisplay(cmdSummary{actions: 23, errors: []string{"one", "two", "three"}})
 	assert.Equal(t, out, fmt.Sprintf("[sum] 23 files, Errored: 3\n"))
 	assert.Equal(t, err, "[sum] Errors encountered: \n\tone\n\ttwo\n\tthree\n")
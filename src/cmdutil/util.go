@@ -58,6 +58,29 @@ type Flags struct {
 	Live                          bool
 	HidePreviewBar                bool
 	DisableThemeKitAccessNotifier bool
+	CSV                           bool
+	Schema                        bool
+	Output                        string
+	Dot                           bool
+	Merge                         bool
+	DryRun                        bool
+	Format                        string
+	From                          string
+	Role                          string
+	Template                      string
+	Mirror                        bool
+	TwoWay                        bool
+	PollInterval                  time.Duration
+	SyncOnStart                   bool
+	Delete                        bool
+	LiveReload                    bool
+	LiveReloadAddr                string
+	Interactive                   bool
+	StatusFile                    string
+	StatusAddr                    string
+	ChangedSince                  string
+	Resume                        bool
+	RetryFailed                   bool
 }
 
 // Ctx is a specific context that a command will run in
@@ -179,12 +202,44 @@ func (ctx *Ctx) DoneTask(op file.Op) {
 	ctx.summary.completeOp(op)
 }
 
+// FailTask will record that the operation on a path failed so that it can be
+// retried later. The error itself should still be reported with Err.
+func (ctx *Ctx) FailTask(path string, op file.Op) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.summary.fail(path, op)
+}
+
+// Failures will return the operations that have been recorded with FailTask
+func (ctx *Ctx) Failures() map[string]file.Op {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	failures := map[string]file.Op{}
+	for path, op := range ctx.summary.failed {
+		failures[path] = op
+	}
+	return failures
+}
+
+// ResumeTasks will record that count units of work were already completed by an
+// earlier run of the command and will not be done again.
+func (ctx *Ctx) ResumeTasks(count int) {
+	ctx.summary.resume(count)
+}
+
 // DisableSummary will ensure that the file operation summary will not output at
 // the end of the operation
 func (ctx *Ctx) DisableSummary() {
 	ctx.summary.disable()
 }
 
+// Errors will return all of the errors that have been reported with Err
+func (ctx *Ctx) Errors() []string {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return append([]string{}, ctx.summary.errors...)
+}
+
 func generateContexts(newClient clientFact, progress *mpb.Progress, flags Flags, args []string) ([]*Ctx, error) {
 	ctxs := []*Ctx{}
 	flagEnv := getFlagEnv(flags)
@@ -386,6 +441,62 @@ func forDefaultClient(newClient clientFact, flags Flags, args []string, handler
 	return err
 }
 
+// ForLocal will run in a context for the first environment without connecting to
+// shopify. This is for commands that only work with the files in the project
+// directory so the environment does not need valid credentials.
+func ForLocal(flags Flags, args []string, handler func(*Ctx) error) error {
+	if err := env.SourceVariables(flags.VariableFilePath); err != nil {
+		return err
+	}
+
+	config, err := env.Load(flags.ConfigPath)
+	if err != nil && os.IsNotExist(err) {
+		config = env.New(flags.ConfigPath)
+	} else if err != nil {
+		return err
+	}
+
+	envName := env.Default.Name
+	if len(flags.Environments) > 0 {
+		envName = flags.Environments[0]
+	}
+
+	// validation errors are ignored because credentials are not needed, only
+	// the project directory has to be valid.
+	flagEnv := getFlagEnv(flags)
+	e, err := config.Get(envName, flagEnv)
+	if e == nil {
+		if e, err = config.Set(envName, flagEnv); e == nil {
+			return err
+		}
+	}
+	if info, err := os.Stat(e.Directory); err != nil || !info.IsDir() {
+		return fmt.Errorf("[%s] invalid project directory %s", colors.Green(e.Name), colors.Yellow(e.Directory))
+	}
+
+	if flags.DisableIgnore {
+		e.IgnoredFiles = []string{}
+		e.Ignores = []string{}
+	}
+
+	ctx := &Ctx{
+		Conf:    &config,
+		Env:     e,
+		Flags:   flags,
+		Args:    args,
+		Log:     colors.ColorStdOut,
+		ErrLog:  colors.ColorStdErr,
+		summary: cmdSummary{},
+	}
+
+	err = handler(ctx)
+	ctx.summary.display(ctx)
+	if err == nil && ctx.summary.hasErrors() {
+		return ErrDuringRuntime
+	}
+	return err
+}
+
 func shopifyThemeClientFactory(e *env.Env) (shopifyClient, error) {
 	client, err := shopify.NewClient(e)
 	if err != nil {
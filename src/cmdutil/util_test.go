@@ -3,6 +3,7 @@ package cmdutil
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"testing"
 
@@ -84,6 +85,28 @@ func TestCtx_Err(t *testing.T) {
 	assert.NotContains(t, stdErr.String(), "[production] this is err")
 }
 
+func TestCtx_Errors(t *testing.T) {
+	ctx := Ctx{Env: &env.Env{}, Flags: Flags{}, ErrLog: log.New(ioutil.Discard, "", 0)}
+	assert.Equal(t, []string{}, ctx.Errors())
+
+	ctx.Err("[%s] this is err", "development")
+	errs := ctx.Errors()
+	assert.Equal(t, []string{"[development] this is err"}, errs)
+	errs[0] = "changed"
+	assert.Equal(t, []string{"[development] this is err"}, ctx.Errors())
+}
+
+func TestCtx_Failures(t *testing.T) {
+	ctx := Ctx{Env: &env.Env{}, Flags: Flags{}}
+	assert.Equal(t, map[string]file.Op{}, ctx.Failures())
+
+	ctx.FailTask("assets/app.js", file.Update)
+	failures := ctx.Failures()
+	assert.Equal(t, map[string]file.Op{"assets/app.js": file.Update}, failures)
+	failures["other.js"] = file.Remove
+	assert.Equal(t, map[string]file.Op{"assets/app.js": file.Update}, ctx.Failures())
+}
+
 func TestCtx_DoneTask(t *testing.T) {
 	ctx := Ctx{Env: &env.Env{}, Flags: Flags{}, progress: mpb.New(nil)}
 	assert.NotPanics(t, func() {
@@ -351,3 +374,46 @@ func TestForDefaultClient(t *testing.T) {
 	assert.Equal(t, gandalfErr, err)
 	assert.Contains(t, stdErr.String(), "Errors encountered: ")
 }
+
+func TestForLocal(t *testing.T) {
+	gandalfErr := fmt.Errorf("you shall not pass")
+
+	var ctx *Ctx
+	err := ForLocal(Flags{}, []string{"a"}, func(c *Ctx) error {
+		ctx = c
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, ctx.Client)
+	assert.Equal(t, []string{"a"}, ctx.Args)
+	assert.Equal(t, env.Default.Directory, ctx.Env.Directory)
+
+	err = ForLocal(Flags{ConfigPath: "_testdata/config.yml", DisableIgnore: true}, []string{}, func(c *Ctx) error {
+		ctx = c
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "123", ctx.Env.ThemeID)
+	assert.Equal(t, []string{}, ctx.Env.IgnoredFiles)
+
+	err = ForLocal(Flags{ConfigPath: "_testdata/config.yml"}, []string{}, func(*Ctx) error { return gandalfErr })
+	assert.Equal(t, gandalfErr, err)
+
+	err = ForLocal(Flags{VariableFilePath: "_testdata/nope"}, []string{}, func(*Ctx) error { return nil })
+	assert.NotNil(t, err)
+
+	err = ForLocal(Flags{Directory: "nope"}, []string{}, func(*Ctx) error { return nil })
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "invalid project directory")
+	}
+
+	stdErr := bytes.NewBufferString("")
+	err = ForLocal(Flags{}, []string{}, func(ctx *Ctx) error {
+		ctx.ErrLog = log.New(stdErr, "", 0)
+		ctx.Err("oopsy")
+		ctx.DoneTask(file.Skip)
+		return nil
+	})
+	assert.Equal(t, ErrDuringRuntime, err)
+	assert.Contains(t, stdErr.String(), "Errors encountered: ")
+}
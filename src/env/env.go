@@ -13,17 +13,26 @@ import (
 
 // Env is the structure of a configuration for an environment.
 type Env struct {
-	Name         string        `yaml:"-" json:"-" env:"-"`
-	Password     string        `yaml:"password,omitempty" json:"password,omitempty" env:"THEMEKIT_PASSWORD"`
-	ThemeID      string        `yaml:"theme_id,omitempty" json:"theme_id,omitempty" env:"THEMEKIT_THEME_ID"`
-	Domain       string        `yaml:"store" json:"store" env:"THEMEKIT_STORE"`
-	Directory    string        `yaml:"directory,omitempty" json:"directory,omitempty" env:"THEMEKIT_DIRECTORY"`
-	IgnoredFiles []string      `yaml:"ignore_files,omitempty" json:"ignore_files,omitempty" env:"THEMEKIT_IGNORE_FILES" envSeparator:":"`
-	Proxy        string        `yaml:"proxy,omitempty" json:"proxy,omitempty" env:"THEMEKIT_PROXY"`
-	Ignores      []string      `yaml:"ignores,omitempty" json:"ignores,omitempty" env:"THEMEKIT_IGNORES" envSeparator:":"`
-	Timeout      time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty" env:"THEMEKIT_TIMEOUT"`
-	ReadOnly     bool          `yaml:"readonly,omitempty" json:"readonly,omitempty" env:"-"`
-	Notify       string        `yaml:"notify,omitempty" json:"notify,omitempty" env:"THEMEKIT_NOTIFY"`
+	Name          string        `yaml:"-" json:"-" env:"-"`
+	Password      string        `yaml:"password,omitempty" json:"password,omitempty" env:"THEMEKIT_PASSWORD"`
+	ThemeID       string        `yaml:"theme_id,omitempty" json:"theme_id,omitempty" env:"THEMEKIT_THEME_ID"`
+	Domain        string        `yaml:"store" json:"store" env:"THEMEKIT_STORE"`
+	Directory     string        `yaml:"directory,omitempty" json:"directory,omitempty" env:"THEMEKIT_DIRECTORY"`
+	IgnoredFiles  []string      `yaml:"ignore_files,omitempty" json:"ignore_files,omitempty" env:"THEMEKIT_IGNORE_FILES" envSeparator:":"`
+	Proxy         string        `yaml:"proxy,omitempty" json:"proxy,omitempty" env:"THEMEKIT_PROXY"`
+	Ignores       []string      `yaml:"ignores,omitempty" json:"ignores,omitempty" env:"THEMEKIT_IGNORES" envSeparator:":"`
+	Timeout       time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty" env:"THEMEKIT_TIMEOUT"`
+	ReadOnly      bool          `yaml:"readonly,omitempty" json:"readonly,omitempty" env:"-"`
+	Notify        string        `yaml:"notify,omitempty" json:"notify,omitempty" env:"THEMEKIT_NOTIFY"`
+	NotifyTargets []string      `yaml:"notify_targets,omitempty" json:"notify_targets,omitempty" env:"THEMEKIT_NOTIFY_TARGETS" envSeparator:","`
+	NotifySecret  string        `yaml:"notify_secret,omitempty" json:"notify_secret,omitempty" env:"THEMEKIT_NOTIFY_SECRET"`
+	// TextExtensions and BinaryExtensions force files with these extensions to be
+	// uploaded as text or binary instead of detecting it from their contents.
+	TextExtensions   []string `yaml:"text_extensions,omitempty" json:"text_extensions,omitempty" env:"THEMEKIT_TEXT_EXTENSIONS" envSeparator:","`
+	BinaryExtensions []string `yaml:"binary_extensions,omitempty" json:"binary_extensions,omitempty" env:"THEMEKIT_BINARY_EXTENSIONS" envSeparator:","`
+	// NormalizeLineEndings converts CRLF line endings in text files to LF before
+	// they are compared with or uploaded to shopify.
+	NormalizeLineEndings bool `yaml:"normalize_line_endings,omitempty" json:"normalize_line_endings,omitempty" env:"THEMEKIT_NORMALIZE_LINE_ENDINGS"`
 }
 
 //Default is the default values for a environment
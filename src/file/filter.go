@@ -23,6 +23,8 @@ var defaultRegexes = []*regexp.Regexp{
 	regexp.MustCompile(`desktop\.ini`),
 	regexp.MustCompile(`config.yml`),
 	regexp.MustCompile(`node_modules`),
+	regexp.MustCompile(`\.themekit-tmp`),
+	regexp.MustCompile(`\.remote$`),
 }
 
 var defaultGlobs = []string{}
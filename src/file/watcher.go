@@ -6,6 +6,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Shopify/themekit/src/env"
@@ -39,7 +42,11 @@ type Event struct {
 	Op                Op
 	Path              string
 	LastKnownChecksum string
-	checksum          string
+	// MovedTo is the new path of a file that was renamed or moved, it is set on the
+	// Remove of its old path so that the old path is only removed once the new path
+	// was uploaded.
+	MovedTo  string
+	checksum string
 }
 
 // Watcher is the object used to watch files for change and notify on any events,
@@ -48,8 +55,11 @@ type Watcher struct {
 	Events chan Event
 
 	fsWatcher *watcher.Watcher
+	filter    watcher.FilterFileHookFunc
 	directory string
+	mu        sync.Mutex
 	checksums map[string]string
+	checksum  func(dir, path string) (string, error)
 }
 
 // NewWatcher will create a new file change watching for a given directory defined
@@ -79,7 +89,9 @@ func NewWatcher(e *env.Env, configPath string, checksums map[string]string) (*Wa
 		Events:    make(chan Event),
 		directory: e.Directory,
 		checksums: checksums,
+		checksum:  FileChecksum,
 		fsWatcher: fsWatcher,
+		filter:    hook,
 	}, nil
 }
 
@@ -143,7 +155,12 @@ func (w *Watcher) onEvent(event watcher.Event) bool {
 			}
 			drainTimer.Reset(drainTimeout)
 		case <-drainTimer.C:
+			batch := []Event{}
 			for _, e := range events {
+				batch = append(batch, e)
+			}
+			SortEvents(batch)
+			for _, e := range batch {
 				w.updateChecksum(e)
 				w.Events <- e
 			}
@@ -153,6 +170,8 @@ func (w *Watcher) onEvent(event watcher.Event) bool {
 }
 
 func (w *Watcher) updateChecksum(e Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if e.Op == Remove {
 		delete(w.checksums, e.Path)
 	} else if e.Op == Update {
@@ -160,27 +179,97 @@ func (w *Watcher) updateChecksum(e Event) {
 	}
 }
 
+// Checksum will return the last known checksum of a file in the project
+func (w *Watcher) Checksum(path string) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.checksums[path]
+}
+
+// SetChecksum will update the last known checksum of a file. This is used when a
+// file is changed by themekit so that the change does not create an event.
+func (w *Watcher) SetChecksum(path, checksum string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.checksums[path] = checksum
+}
+
+// SetChecksumFunc will change how the checksum of a changed file is calculated so
+// that it can be compared with checksums that were not calculated with
+// FileChecksum, like the checksums from shopify. It must be set before Watch.
+func (w *Watcher) SetChecksumFunc(checksum func(dir, path string) (string, error)) {
+	w.checksum = checksum
+}
+
 func (w *Watcher) translateEvent(event watcher.Event) []Event {
 	oldPath, currentPath := w.parsePath(event.OldPath), w.parsePath(event.Path)
 	if event.IsDir() {
 		if isEventType(event.Op, watcher.Create) {
 			w.fsWatcher.Add(event.Path)
+		} else if isEventType(event.Op, watcher.Rename, watcher.Move) {
+			return w.translateFolderMove(event.OldPath, event.Path)
 		}
 	} else if isEventType(event.Op, watcher.Rename, watcher.Move) {
-		return []Event{{Op: Remove, Path: oldPath}, {Op: Update, Path: currentPath, LastKnownChecksum: w.checksums[currentPath]}}
+		return []Event{{Op: Remove, Path: oldPath, MovedTo: currentPath}, w.updateEvent(currentPath)}
 	} else if isEventType(event.Op, watcher.Remove) {
 		return []Event{{Op: Remove, Path: currentPath}}
 	} else if isEventType(event.Op, watcher.Create, watcher.Write) {
-		checksum, err := fileChecksum(w.directory, currentPath)
-		eventOp := Update
-		if err == nil && checksum == w.checksums[currentPath] {
-			eventOp = Skip
-		}
-		return []Event{{Op: eventOp, Path: currentPath, checksum: checksum, LastKnownChecksum: w.checksums[currentPath]}}
+		return []Event{w.updateEvent(currentPath)}
 	}
 	return []Event{}
 }
 
+func (w *Watcher) updateEvent(path string) Event {
+	checksum, err := w.checksum(w.directory, path)
+	lastKnown := w.Checksum(path)
+	eventOp := Update
+	if err == nil && checksum == lastKnown {
+		eventOp = Skip
+	}
+	return Event{Op: eventOp, Path: path, checksum: checksum, LastKnownChecksum: lastKnown}
+}
+
+// translateFolderMove will turn a folder that was renamed or moved into an update
+// for every file in its new location and a remove for every file in its old
+// location, so that the whole folder is moved as one batch.
+func (w *Watcher) translateFolderMove(oldDir, currentDir string) []Event {
+	w.fsWatcher.Remove(oldDir)
+	w.fsWatcher.Add(currentDir)
+
+	events := []Event{}
+	filepath.Walk(currentDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || strings.HasPrefix(info.Name(), ".") || w.filter(info, path) != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(currentDir, path)
+		if err != nil {
+			return nil
+		}
+		currentPath := pathToProject(w.directory, path)
+		if currentPath != "" {
+			events = append(events, w.updateEvent(currentPath))
+		}
+		if oldPath := pathToProject(w.directory, filepath.Join(oldDir, rel)); oldPath != "" {
+			events = append(events, Event{Op: Remove, Path: oldPath, MovedTo: currentPath})
+		}
+		return nil
+	})
+	return events
+}
+
+// SortEvents will order events so that every file is uploaded before any file is
+// removed. When files are renamed or moved, the new keys exist on shopify before
+// the old keys are deleted so that the theme never misses a file. If the upload of
+// a new key fails, the remove of its old key should be held, see Event.MovedTo.
+func SortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if (events[i].Op == Remove) != (events[j].Op == Remove) {
+			return events[j].Op == Remove
+		}
+		return events[i].Path < events[j].Path
+	})
+}
+
 func (w *Watcher) parsePath(path string) string {
 	projectPath := pathToProject(w.directory, path)
 	if projectPath == "" {
@@ -207,7 +296,9 @@ func (w *Watcher) Stop() {
 	}
 }
 
-func fileChecksum(dir, src string) (string, error) {
+// FileChecksum will return the md5 checksum of a file in dir, the same checksum
+// that the watcher uses to tell if a file has changed.
+func FileChecksum(dir, src string) (string, error) {
 	sum := md5.New()
 	s, err := os.Open(filepath.Join(dir, src))
 	if err != nil {
@@ -2,6 +2,7 @@ package file
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
@@ -55,7 +56,7 @@ func TestFileWatcher_NoEventIfFileDidntChange(t *testing.T) {
 	}
 	path := filepath.Join("_testdata", "project", "assets", "application.js")
 	shortPath := filepath.Join("assets", "application.js")
-	currentChecksum, _ := fileChecksum(e.Directory, shortPath)
+	currentChecksum, _ := FileChecksum(e.Directory, shortPath)
 
 	w, _ := NewWatcher(e, "", map[string]string{
 		shortPath: currentChecksum,
@@ -203,6 +204,67 @@ func TestFileWatcher_translateEvent(t *testing.T) {
 	}
 }
 
+func TestFileWatcher_translateFolderMove(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "watcher")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "snippets", "product"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "snippets", "product", "price.liquid"), []byte("price"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "snippets", "product", "title.liquid"), []byte("title"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "snippets", "product", ".hidden"), []byte(""), 0644)
+
+	w, err := NewWatcher(&env.Env{Directory: dir}, "", map[string]string{})
+	assert.Nil(t, err)
+	defer w.Stop()
+
+	oldDir, currentDir := filepath.Join(dir, "snippets", "item"), filepath.Join(dir, "snippets", "product")
+	info, _ := os.Stat(currentDir)
+	events := w.translateEvent(watcher.Event{Op: watcher.Rename, Path: currentDir, OldPath: oldDir, FileInfo: info})
+	SortEvents(events)
+
+	paths := []string{}
+	ops := []Op{}
+	movedTo := []string{}
+	for _, e := range events {
+		paths = append(paths, e.Path)
+		ops = append(ops, e.Op)
+		movedTo = append(movedTo, e.MovedTo)
+	}
+	assert.Equal(t, []string{"snippets/product/price.liquid", "snippets/product/title.liquid", "snippets/item/price.liquid", "snippets/item/title.liquid"}, paths)
+	assert.Equal(t, []Op{Update, Update, Remove, Remove}, ops)
+	assert.Equal(t, []string{"", "", "snippets/product/price.liquid", "snippets/product/title.liquid"}, movedTo)
+}
+
+func TestSortEvents(t *testing.T) {
+	events := []Event{
+		{Op: Remove, Path: "snippets/b.liquid"},
+		{Op: Update, Path: "snippets/sub/b.liquid"},
+		{Op: Remove, Path: "snippets/a.liquid"},
+		{Op: Skip, Path: "assets/app.js"},
+		{Op: Update, Path: "snippets/sub/a.liquid"},
+	}
+	SortEvents(events)
+	assert.Equal(t, []Event{
+		{Op: Skip, Path: "assets/app.js"},
+		{Op: Update, Path: "snippets/sub/a.liquid"},
+		{Op: Update, Path: "snippets/sub/b.liquid"},
+		{Op: Remove, Path: "snippets/a.liquid"},
+		{Op: Remove, Path: "snippets/b.liquid"},
+	}, events)
+}
+
+func TestFileWatcher_OnEventOrder(t *testing.T) {
+	w := createTestWatcher(t)
+	w.Events = make(chan Event, 2)
+	defer w.Stop()
+	path := filepath.Join("_testdata", "project", "assets", "application.js.liquid")
+	info, _ := os.Stat(path)
+	w.onEvent(watcher.Event{Op: watcher.Rename, Path: path, OldPath: filepath.Join("_testdata", "project", "assets", "application.js"), FileInfo: info})
+	assert.Equal(t, Update, (<-w.Events).Op)
+	remove := <-w.Events
+	assert.Equal(t, Remove, remove.Op)
+	assert.Equal(t, "assets/application.js.liquid", remove.MovedTo)
+}
+
 func TestFileWatcher_debouncing(t *testing.T) {
 	w := createTestWatcher(t)
 	w.Events = make(chan Event, 10)
@@ -232,6 +294,24 @@ func TestFileWatcher_ParsePath(t *testing.T) {
 	}
 }
 
+func TestFileWatcher_Checksum(t *testing.T) {
+	w := createTestWatcher(t)
+	assert.Equal(t, "", w.Checksum("assets/app.js"))
+	w.SetChecksum("assets/app.js", "abc")
+	assert.Equal(t, "abc", w.Checksum("assets/app.js"))
+	w.updateChecksum(Event{Op: Remove, Path: "assets/app.js"})
+	assert.Equal(t, "", w.Checksum("assets/app.js"))
+}
+
+func TestFileWatcher_SetChecksumFunc(t *testing.T) {
+	w := createTestWatcher(t)
+	w.SetChecksumFunc(func(dir, path string) (string, error) { return "canonical", nil })
+	w.SetChecksum("assets/application.js", "canonical")
+	evt := w.updateEvent("assets/application.js")
+	assert.Equal(t, Skip, evt.Op)
+	assert.Equal(t, "canonical", evt.checksum)
+}
+
 func TestIsEventType(t *testing.T) {
 	expectedOps := []watcher.Op{watcher.Write, watcher.Remove, watcher.Rename}
 	refutedOps := []watcher.Op{watcher.Chmod, watcher.Create, watcher.Move}
@@ -0,0 +1,187 @@
+// Package generate renders the boilerplate for new theme files. Every kind of file
+// has a default template which can be overridden by a project by adding a
+// <kind>.tmpl file to .themekit/generators.
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// The kinds of files that can be generated
+const (
+	Section  = "section"
+	Snippet  = "snippet"
+	Template = "template"
+	Locale   = "locale"
+)
+
+// OverrideDir is the directory in a project that generator templates are loaded
+// from before falling back to the defaults.
+const OverrideDir = ".themekit/generators"
+
+// Generator templates use different delimiters than the default text/template
+// ones so that they do not conflict with liquid, which are the same as starters.
+const (
+	leftDelim  = "{{%"
+	rightDelim = "%}}"
+)
+
+var (
+	nameRegex        = regexp.MustCompile(`[^a-z0-9.]+`)
+	translationRegex = regexp.MustCompile(`['"]([\w-]+(?:\.[\w-]+)+)['"]\s*\|\s*t\b`)
+)
+
+var defaults = map[string]string{
+	Section: `{%- liquid
+  assign heading = section.settings.heading
+  if heading == blank
+    assign heading = 'sections.{{% .Key %}}.title' | t
+  endif
+-%}
+<section class="section-{{% .Handle %}}">
+  <h2>{{ heading }}</h2>
+  {%- for block in section.blocks %}
+    <div {{ block.shopify_attributes }}>{{ block.settings.text }}</div>
+  {%- endfor %}
+</section>
+
+{% schema %}
+{
+  "name": "{{% .Title %}}",
+  "tag": "section",
+  "class": "section-{{% .Handle %}}",
+  "settings": [
+    {
+      "type": "text",
+      "id": "heading",
+      "label": "Heading"
+    }
+  ],
+  "blocks": [
+    {
+      "type": "text",
+      "name": "Text",
+      "settings": [
+        {
+          "type": "text",
+          "id": "text",
+          "label": "Text"
+        }
+      ]
+    }
+  ],
+  "presets": [
+    {
+      "name": "{{% .Title %}}"
+    }
+  ]
+}
+{% endschema %}
+`,
+	Snippet: `{% comment %}
+  Renders {{% .Title %}}
+
+  Usage:
+  {% render '{{% .Handle %}}' %}
+{% endcomment %}
+<div class="{{% .Handle %}}">
+  {{ 'snippets.{{% .Key %}}.title' | t }}
+</div>
+`,
+	Template: `<div class="template-{{% .Handle %}}">
+  <h1>{{ 'templates.{{% .Key %}}.title' | t }}</h1>
+</div>
+`,
+	Locale: "{}\n",
+}
+
+// Data is what is available to generator templates
+type Data struct {
+	// Name is the name that was passed to the generator
+	Name string
+	// Handle is the name in lower case with dashes, used for file names
+	Handle string
+	// Key is the handle with underscores, used for translation keys
+	Key string
+	// Title is the name in title case, used for labels
+	Title string
+}
+
+// NewData will build the template data for a name
+func NewData(name string) Data {
+	handle := strings.Trim(nameRegex.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+	words := strings.FieldsFunc(handle, func(r rune) bool { return r == '-' || r == '.' })
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return Data{
+		Name:   name,
+		Handle: handle,
+		Key:    strings.NewReplacer("-", "_", ".", "_").Replace(handle),
+		Title:  strings.Join(words, " "),
+	}
+}
+
+// Path will return the asset key of a generated file
+func Path(kind string, data Data) (string, error) {
+	switch kind {
+	case Section:
+		return path.Join("sections", data.Handle+".liquid"), nil
+	case Snippet:
+		return path.Join("snippets", data.Handle+".liquid"), nil
+	case Template:
+		return path.Join("templates", data.Handle+".liquid"), nil
+	case Locale:
+		return path.Join("locales", data.Handle+".json"), nil
+	}
+	return "", fmt.Errorf("unknown generator %s", kind)
+}
+
+// Render will render the template for kind, using the template in the project
+// directory if it exists.
+func Render(dir, kind string, data Data) ([]byte, error) {
+	source, ok := defaults[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown generator %s", kind)
+	}
+
+	overridePath := filepath.Join(dir, filepath.FromSlash(OverrideDir), kind+".tmpl")
+	if override, err := ioutil.ReadFile(overridePath); err == nil {
+		source = string(override)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	tmpl, err := template.New(kind).Delims(leftDelim, rightDelim).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s generator: %s", kind, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("could not render %s generator: %s", kind, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// TranslationKeys will return the keys of all the translations that are used with
+// the t filter in the contents of a liquid file.
+func TranslationKeys(contents []byte) []string {
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, match := range translationRegex.FindAllSubmatch(contents, -1) {
+		if key := string(match[1]); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
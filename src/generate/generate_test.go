@@ -0,0 +1,79 @@
+package generate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewData(t *testing.T) {
+	assert.Equal(t, Data{Name: "Hero Banner", Handle: "hero-banner", Key: "hero_banner", Title: "Hero Banner"}, NewData("Hero Banner"))
+	assert.Equal(t, Data{Name: "product.alternate", Handle: "product.alternate", Key: "product_alternate", Title: "Product Alternate"}, NewData("product.alternate"))
+	assert.Equal(t, Data{Name: "fr", Handle: "fr", Key: "fr", Title: "Fr"}, NewData("fr"))
+	assert.Equal(t, "", NewData("!!!").Handle)
+}
+
+func TestPath(t *testing.T) {
+	data := NewData("hero")
+	testcases := map[string]string{
+		Section:  "sections/hero.liquid",
+		Snippet:  "snippets/hero.liquid",
+		Template: "templates/hero.liquid",
+		Locale:   "locales/hero.json",
+	}
+	for kind, expected := range testcases {
+		key, err := Path(kind, data)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, key)
+	}
+
+	_, err := Path("block", data)
+	assert.EqualError(t, err, "unknown generator block")
+}
+
+func TestRender(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "generate")
+	defer os.RemoveAll(dir)
+	data := NewData("Hero Banner")
+
+	contents, err := Render(dir, Section, data)
+	assert.Nil(t, err)
+	assert.Contains(t, string(contents), `<section class="section-hero-banner">`)
+	assert.Contains(t, string(contents), `'sections.hero_banner.title' | t`)
+	assert.Contains(t, string(contents), "\"presets\": [\n    {\n      \"name\": \"Hero Banner\"")
+
+	contents, err = Render(dir, Snippet, data)
+	assert.Nil(t, err)
+	assert.Contains(t, string(contents), "{% render 'hero-banner' %}")
+
+	override := filepath.Join(dir, filepath.FromSlash(OverrideDir))
+	os.MkdirAll(override, 0755)
+	ioutil.WriteFile(filepath.Join(override, "snippet.tmpl"), []byte("<p>{{ '{{% .Key %}}.label' | t }}</p>"), 0644)
+	contents, err = Render(dir, Snippet, data)
+	assert.Nil(t, err)
+	assert.Equal(t, "<p>{{ 'hero_banner.label' | t }}</p>", string(contents))
+
+	ioutil.WriteFile(filepath.Join(override, "template.tmpl"), []byte("{{% .Nope %}}"), 0644)
+	_, err = Render(dir, Template, data)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not render template generator")
+	}
+
+	ioutil.WriteFile(filepath.Join(override, "template.tmpl"), []byte("{{% if %}}"), 0644)
+	_, err = Render(dir, Template, data)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not parse template generator")
+	}
+
+	_, err = Render(dir, "block", data)
+	assert.EqualError(t, err, "unknown generator block")
+}
+
+func TestTranslationKeys(t *testing.T) {
+	contents := []byte(`{{ 'sections.hero.title' | t }} {{ "general.close" |t }} {{ 'sections.hero.title' | t }}
+{{ 'not a key' | t }} {{ 'products.price' | money }} {{ 'single' | t }}`)
+	assert.Equal(t, []string{"sections.hero.title", "general.close"}, TranslationKeys(contents))
+}
@@ -22,7 +22,11 @@ var (
 	ErrConnectionIssue = errors.New("DNS problem while connecting to Shopify, this indicates a problem with your internet connection")
 	// ErrInvalidProxyURL is returned if a proxy url has been passed but is improperly formatted
 	ErrInvalidProxyURL = errors.New("invalid proxy URI")
-	httpTransport      = &http.Transport{
+	// ErrRequestFailed is wrapped by the error returned when a request still fails
+	// after all of its retries because of a network or server error. The request
+	// may succeed if it is tried again later.
+	ErrRequestFailed = errors.New("request failed")
+	httpTransport    = &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
 	httpClient = &http.Client{
@@ -156,7 +160,7 @@ func (client *HTTPClient) doWithRetry(req *http.Request, body interface{}) (*htt
 		time.Sleep(time.Duration(attempt) * time.Second)
 	}
 
-	return nil, fmt.Errorf("request failed after %v retries with error: %v", client.maxRetry, err)
+	return nil, fmt.Errorf("%w after %v retries with error: %v", ErrRequestFailed, client.maxRetry, err)
 }
 
 func parseBaseURL(domain string) (*url.URL, error) {
@@ -2,6 +2,7 @@ package httpify
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -140,6 +141,7 @@ func TestClient_do(t *testing.T) {
 
 	_, err = client.do("POST", "/assets.json", body, nil)
 	assert.Contains(t, err.Error(), "request failed after 1 retries", server.URL)
+	assert.True(t, errors.Is(err, ErrRequestFailed))
 	server.Close()
 
 	// Client should query Theme Access server instead of Shopify when password starts with a prefix "shptka_"
@@ -0,0 +1,343 @@
+// Package locales allows for comparing, exporting and importing the translation
+// files that live in the locales directory of a theme.
+package locales
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Problem kinds reported by Compare
+const (
+	// Missing is reported when a key in the default locale is not translated
+	Missing = "missing"
+	// Extra is reported when a key does not exist in the default locale
+	Extra = "extra"
+	// Empty is reported when a key exists but has no translation
+	Empty = "empty"
+	// Interpolation is reported when the {{ variables }} of a translation do not
+	// match the variables of the default locale
+	Interpolation = "interpolation"
+)
+
+var (
+	// ErrNoDefaultLocale is returned when none of the locale files is marked as default
+	ErrNoDefaultLocale = errors.New("no default locale found, one file must be named [locale].default.json")
+	// ErrInvalidCSV is returned when an imported csv does not have a key column
+	ErrInvalidCSV = errors.New("invalid csv, the first column of the header must be 'key'")
+
+	interpolationRegex = regexp.MustCompile(`{{-?\s*([\w.-]+)\s*-?}}`)
+	pluralCategories   = map[string]bool{"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true}
+)
+
+// Locale is a single translation file from the locales directory
+type Locale struct {
+	Key     string
+	Name    string
+	Default bool
+	Schema  bool
+	values  map[string]interface{}
+}
+
+// Problem describes a single difference between a locale and the default locale
+type Problem struct {
+	Kind   string
+	Key    string
+	Detail string
+}
+
+// New will create an empty locale for an asset key like locales/fr.json
+func New(key string) Locale {
+	name := strings.TrimSuffix(path.Base(key), ".json")
+	return Locale{
+		Key:     key,
+		Name:    name,
+		Default: strings.Contains(name, ".default"),
+		Schema:  strings.HasSuffix(name, ".schema"),
+		values:  map[string]interface{}{},
+	}
+}
+
+// Parse will create a locale from the asset key and the JSON contents of the file
+func Parse(key, value string) (Locale, error) {
+	locale := New(key)
+	if err := json.Unmarshal([]byte(value), &locale.values); err != nil {
+		return locale, fmt.Errorf("could not parse %s: %s", key, err)
+	}
+	return locale, nil
+}
+
+// FindDefault will return the default locale out of a set of locales. Schema
+// locales are matched with the default schema locale.
+func FindDefault(locales []Locale, schema bool) (Locale, error) {
+	for _, locale := range locales {
+		if locale.Default && locale.Schema == schema {
+			return locale, nil
+		}
+	}
+	return Locale{}, ErrNoDefaultLocale
+}
+
+// Flatten will return all of the translations in the locale keyed by their
+// dot separated path. Values that are not strings, like numbers, booleans and
+// lists, are returned as JSON.
+func (l Locale) Flatten() map[string]string {
+	flat := map[string]string{}
+	flatten("", l.values, flat)
+	return flat
+}
+
+func flatten(prefix string, values map[string]interface{}, flat map[string]string) {
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flatten(key, v, flat)
+		case string:
+			flat[key] = v
+		case nil:
+			flat[key] = ""
+		default:
+			data, _ := json.Marshal(v)
+			flat[key] = string(data)
+		}
+	}
+}
+
+// Set will set a translation at a dot separated path, creating any of the
+// parent objects that do not exist yet.
+func (l *Locale) Set(key, value string) {
+	l.set(key, value)
+}
+
+func (l *Locale) set(key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	current := l.values
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := current[part].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			current[part] = next
+		}
+		current = next
+	}
+	current[parts[len(parts)-1]] = value
+}
+
+// Has will return true if the locale has a value at the dot separated path
+func (l Locale) Has(key string) bool {
+	_, ok := l.get(key)
+	return ok
+}
+
+func (l Locale) get(key string) (interface{}, bool) {
+	parts := strings.Split(key, ".")
+	current := l.values
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := current[part].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	value, ok := current[parts[len(parts)-1]]
+	return value, ok
+}
+
+// Delete will remove the translation at a dot separated path and clean up any
+// parent objects that are left empty.
+func (l *Locale) Delete(key string) {
+	deleteKey(l.values, strings.Split(key, "."))
+}
+
+func deleteKey(values map[string]interface{}, parts []string) {
+	if len(parts) == 1 {
+		delete(values, parts[0])
+		return
+	}
+	if next, ok := values[parts[0]].(map[string]interface{}); ok {
+		deleteKey(next, parts[1:])
+		if len(next) == 0 {
+			delete(values, parts[0])
+		}
+	}
+}
+
+// Marshal will output the locale as indented JSON. Keys are always sorted so
+// that rebuilding a file produces a stable output.
+func (l Locale) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(l.values)
+	return buf.Bytes(), err
+}
+
+// Compare will check a locale against the default locale and report any missing,
+// extra or empty translations as well as translations that do not use the same
+// interpolation variables as the default.
+func Compare(def, other Locale) []Problem {
+	problems := []Problem{}
+	defaults, translations := def.Flatten(), other.Flatten()
+
+	for _, key := range sortedKeys(defaults) {
+		value, ok := translations[key]
+		if !ok {
+			problems = append(problems, Problem{Kind: Missing, Key: key})
+		} else if strings.TrimSpace(value) == "" {
+			problems = append(problems, Problem{Kind: Empty, Key: key})
+		} else if expected, got := interpolations(defaults[key]), interpolations(value); strings.Join(expected, ",") != strings.Join(got, ",") {
+			problems = append(problems, Problem{
+				Kind:   Interpolation,
+				Key:    key,
+				Detail: fmt.Sprintf("expected [%s] but found [%s]", strings.Join(expected, ", "), strings.Join(got, ", ")),
+			})
+		}
+	}
+
+	for _, key := range sortedKeys(translations) {
+		if _, ok := defaults[key]; !ok && !isPluralForm(def, key) {
+			problems = append(problems, Problem{Kind: Extra, Key: key})
+		}
+	}
+
+	return problems
+}
+
+// isPluralForm will allow languages to define plural categories that the default
+// locale does not need, for instance "few" and "many".
+func isPluralForm(def Locale, key string) bool {
+	index := strings.LastIndex(key, ".")
+	if index < 0 || !pluralCategories[key[index+1:]] {
+		return false
+	}
+	parent := key[:index]
+	for _, category := range []string{"one", "other"} {
+		if def.Has(parent + "." + category) {
+			return true
+		}
+	}
+	return false
+}
+
+func interpolations(value string) []string {
+	found := map[string]bool{}
+	for _, match := range interpolationRegex.FindAllStringSubmatch(value, -1) {
+		found[match[1]] = true
+	}
+	vars := []string{}
+	for name := range found {
+		vars = append(vars, name)
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+// WriteCSV will write all of the locales into a single csv with one row per key
+// and one column per locale. The default locale is always the first column.
+func WriteCSV(w io.Writer, def Locale, others []Locale) error {
+	all := append([]Locale{def}, others...)
+	flattened := make([]map[string]string, len(all))
+	header := []string{"key"}
+	for i, locale := range all {
+		flattened[i] = locale.Flatten()
+		header = append(header, locale.Name)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(flattened[0]) {
+		row := []string{key}
+		for _, values := range flattened {
+			row = append(row, values[key])
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadCSV will read a csv in the format produced by WriteCSV and return the
+// translations keyed by locale name and then by translation key.
+func ReadCSV(r io.Reader) (map[string]map[string]string, error) {
+	translations := map[string]map[string]string{}
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return translations, err
+	} else if len(rows) == 0 || len(rows[0]) < 2 || rows[0][0] != "key" {
+		return translations, ErrInvalidCSV
+	}
+
+	header := rows[0]
+	for _, name := range header[1:] {
+		translations[name] = map[string]string{}
+	}
+	for _, row := range rows[1:] {
+		if len(row) == 0 || row[0] == "" {
+			continue
+		}
+		for i, name := range header[1:] {
+			if i+1 < len(row) {
+				translations[name][row[0]] = row[i+1]
+			}
+		}
+	}
+	return translations, nil
+}
+
+// Import will apply imported translations to the locale. Blank translations
+// remove the key so that they are reported as missing instead of being empty.
+// Keys that hold a value that is not a string, like a number, are decoded from
+// JSON the same way Flatten encoded them so that they keep their type.
+func (l *Locale) Import(translations map[string]string) {
+	for _, key := range sortedKeys(translations) {
+		value := translations[key]
+		if value == "" {
+			if !l.Default {
+				l.Delete(key)
+			}
+			continue
+		}
+		if current, ok := l.get(key); ok && !isText(current) {
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+				l.set(key, decoded)
+				continue
+			}
+		}
+		l.Set(key, value)
+	}
+}
+
+// isText will return true for the values that Flatten does not encode as JSON
+func isText(value interface{}) bool {
+	switch value.(type) {
+	case string, nil, map[string]interface{}:
+		return true
+	}
+	return false
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
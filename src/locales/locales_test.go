@@ -0,0 +1,170 @@
+package locales
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const defaultLocale = `{
+  "general": {
+    "title": "Welcome to {{ shop_name }}",
+    "empty": "Nothing here"
+  },
+  "cart": {
+    "items": {
+      "one": "{{ count }} item",
+      "other": "{{ count }} items"
+    }
+  }
+}`
+
+func TestNew(t *testing.T) {
+	testcases := []struct {
+		key, name       string
+		isDef, isSchema bool
+	}{
+		{key: "locales/en.default.json", name: "en.default", isDef: true},
+		{key: "locales/fr.json", name: "fr"},
+		{key: "locales/en.default.schema.json", name: "en.default.schema", isDef: true, isSchema: true},
+		{key: "locales/de.schema.json", name: "de.schema", isSchema: true},
+	}
+
+	for _, testcase := range testcases {
+		locale := New(testcase.key)
+		assert.Equal(t, testcase.name, locale.Name)
+		assert.Equal(t, testcase.isDef, locale.Default)
+		assert.Equal(t, testcase.isSchema, locale.Schema)
+	}
+}
+
+func TestParse(t *testing.T) {
+	locale, err := Parse("locales/en.default.json", defaultLocale)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"general.title":    "Welcome to {{ shop_name }}",
+		"general.empty":    "Nothing here",
+		"cart.items.one":   "{{ count }} item",
+		"cart.items.other": "{{ count }} items",
+	}, locale.Flatten())
+
+	_, err = Parse("locales/fr.json", "{")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not parse locales/fr.json")
+	}
+}
+
+func TestFindDefault(t *testing.T) {
+	locales := []Locale{New("locales/fr.json"), New("locales/en.default.schema.json"), New("locales/en.default.json")}
+
+	def, err := FindDefault(locales, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "locales/en.default.json", def.Key)
+
+	def, err = FindDefault(locales, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "locales/en.default.schema.json", def.Key)
+
+	_, err = FindDefault(locales[:1], false)
+	assert.Equal(t, ErrNoDefaultLocale, err)
+}
+
+func TestLocale_SetHasDelete(t *testing.T) {
+	locale := New("locales/fr.json")
+	locale.Set("general.title", "Bienvenue")
+	locale.Set("general.empty", "Rien")
+	assert.True(t, locale.Has("general.title"))
+	assert.False(t, locale.Has("general.nope"))
+	assert.False(t, locale.Has("nope.title"))
+
+	locale.Delete("general.title")
+	assert.False(t, locale.Has("general.title"))
+	locale.Delete("general.empty")
+	assert.False(t, locale.Has("general"))
+}
+
+func TestLocale_Marshal(t *testing.T) {
+	locale := New("locales/fr.json")
+	locale.Set("b.title", "<b>Bienvenue</b>")
+	locale.Set("a", "Rien")
+	data, err := locale.Marshal()
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n  \"a\": \"Rien\",\n  \"b\": {\n    \"title\": \"<b>Bienvenue</b>\"\n  }\n}\n", string(data))
+}
+
+func TestCompare(t *testing.T) {
+	def, _ := Parse("locales/en.default.json", defaultLocale)
+	other, _ := Parse("locales/pl.json", `{
+		"general": {"title": "Witaj w {{ shop }}", "empty": " "},
+		"cart": {"items": {"one": "{{ count }} produkt", "few": "{{ count }} produkty"}},
+		"footer": "Stopka"
+	}`)
+
+	assert.Equal(t, []Problem{
+		{Kind: Missing, Key: "cart.items.other"},
+		{Kind: Empty, Key: "general.empty"},
+		{Kind: Interpolation, Key: "general.title", Detail: "expected [shop_name] but found [shop]"},
+		{Kind: Extra, Key: "footer"},
+	}, Compare(def, other))
+
+	assert.Equal(t, []Problem{}, Compare(def, def))
+}
+
+func TestWriteCSV(t *testing.T) {
+	def, _ := Parse("locales/en.default.json", `{"a": "Hello", "b": {"c": "Bye, \"friend\""}}`)
+	fr, _ := Parse("locales/fr.json", `{"a": "Bonjour"}`)
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteCSV(&buf, def, []Locale{fr}))
+	assert.Equal(t, "key,en.default,fr\na,Hello,Bonjour\nb.c,\"Bye, \"\"friend\"\"\",\n", buf.String())
+}
+
+func TestReadCSV(t *testing.T) {
+	translations, err := ReadCSV(strings.NewReader("key,en.default,fr\na,Hello,Bonjour\nb.c,Bye,\n,skipped,row\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"en.default": {"a": "Hello", "b.c": "Bye"},
+		"fr":         {"a": "Bonjour", "b.c": ""},
+	}, translations)
+
+	_, err = ReadCSV(strings.NewReader("name,fr\na,b\n"))
+	assert.Equal(t, ErrInvalidCSV, err)
+
+	_, err = ReadCSV(strings.NewReader("key,\"fr\n"))
+	assert.NotNil(t, err)
+}
+
+func TestLocale_Import(t *testing.T) {
+	fr, _ := Parse("locales/fr.json", `{"a": "Bonjour", "b": {"c": "Au revoir"}, "d": "Garde"}`)
+	fr.Import(map[string]string{"a": "Salut", "b.c": "", "e.f": "Nouveau"})
+	assert.Equal(t, map[string]string{"a": "Salut", "d": "Garde", "e.f": "Nouveau"}, fr.Flatten())
+
+	def, _ := Parse("locales/en.default.json", `{"a": "Hello"}`)
+	def.Import(map[string]string{"a": ""})
+	assert.Equal(t, map[string]string{"a": "Hello"}, def.Flatten())
+}
+
+func TestLocale_ImportRoundTrip(t *testing.T) {
+	source := `{"a": "Hello", "count": 3, "enabled": true, "sizes": ["s", "m"], "b": {"ratio": 1.5}}`
+	def, _ := Parse("locales/en.default.json", source)
+	fr, _ := Parse("locales/fr.json", source)
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteCSV(&buf, def, []Locale{fr}))
+	translations, err := ReadCSV(&buf)
+	assert.Nil(t, err)
+
+	def.Import(translations["en.default"])
+	fr.Import(translations["fr"])
+	expected, _ := Parse("locales/en.default.json", source)
+	expectedJSON, _ := expected.Marshal()
+	defJSON, _ := def.Marshal()
+	frJSON, _ := fr.Marshal()
+	assert.Equal(t, string(expectedJSON), string(defJSON))
+	assert.Equal(t, string(expectedJSON), string(frJSON))
+
+	fr.Import(map[string]string{"count": "not a number"})
+	assert.Equal(t, "not a number", fr.Flatten()["count"])
+}
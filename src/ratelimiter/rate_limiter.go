@@ -8,10 +8,17 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var domainLimitMap = make(map[string]*Limiter)
+var (
+	domainLimitMap = make(map[string]*Limiter)
+	// domainLimitMu guards domainLimitMap, limiters are created by clients while the
+	// watch status server looks them up
+	domainLimitMu sync.RWMutex
+)
 
 // Limiter keeps track of an api rate limit and wont let you pass the limit
 type Limiter struct {
@@ -21,10 +28,25 @@ type Limiter struct {
 	ctx       context.Context
 	cancel    context.CancelFunc
 	locked    bool
+	throttled int64
+	// pausedUntil is the unix nano time that requests are paused until after a 429
+	pausedUntil int64
+}
+
+// State describes how a limiter is limiting requests
+type State struct {
+	// RequestsPerSecond is the number of requests allowed every second
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	// Throttled is how many requests shopify has responded to with a 429
+	Throttled int64 `json:"throttled"`
+	// PausedUntil is set while requests are paused because of a 429
+	PausedUntil *time.Time `json:"paused_until,omitempty"`
 }
 
 // New creates a new call rate limiter for a single domain
 func New(domain string, reqPerSec int) *Limiter {
+	domainLimitMu.Lock()
+	defer domainLimitMu.Unlock()
 	if _, ok := domainLimitMap[domain]; !ok {
 		everySecond := rate.Every(time.Second / time.Duration(reqPerSec))
 		ctx, cancel := context.WithCancel(context.Background())
@@ -38,6 +60,26 @@ func New(domain string, reqPerSec int) *Limiter {
 	return domainLimitMap[domain]
 }
 
+// Lookup will return the limiter for a domain if a client has been created for it
+func Lookup(domain string) (*Limiter, bool) {
+	domainLimitMu.RLock()
+	defer domainLimitMu.RUnlock()
+	limiter, ok := domainLimitMap[domain]
+	return limiter, ok
+}
+
+// State will return how the limiter is currently limiting requests
+func (limiter *Limiter) State() State {
+	state := State{
+		RequestsPerSecond: float64(limiter.perSecond),
+		Throttled:         atomic.LoadInt64(&limiter.throttled),
+	}
+	if until := time.Unix(0, atomic.LoadInt64(&limiter.pausedUntil)); until.After(time.Now()) {
+		state.PausedUntil = &until
+	}
+	return state
+}
+
 // GateReq will make the http request but will force it to comply with concurrent limits,
 // rate limits, and it will also retry requests that receive 429.
 // When a 429 occurs, it will cancel all inflight requests and pauses, so that the requests
@@ -64,6 +106,8 @@ func (limiter *Limiter) retryAfter(header string) {
 	limiter.lock()
 	defer limiter.unlock()
 	after, _ := strconv.ParseFloat(header, 10)
+	atomic.AddInt64(&limiter.throttled, 1)
+	atomic.StoreInt64(&limiter.pausedUntil, time.Now().Add(time.Duration(after)*time.Second).UnixNano())
 	time.Sleep(time.Duration(after) * time.Second)
 }
 
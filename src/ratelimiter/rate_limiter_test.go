@@ -1,6 +1,7 @@
 package ratelimiter
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -16,6 +17,22 @@ func TestRateLimiterForDomain(t *testing.T) {
 	assert.NotEqual(t, limiter2, limiter3)
 }
 
+func TestRateLimiterConcurrentLookup(t *testing.T) {
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			Lookup("concurrent.com")
+		}
+		done <- true
+	}()
+	for i := 0; i < 100; i++ {
+		New(fmt.Sprintf("concurrent%d.com", i), 1)
+	}
+	<-done
+	_, ok := Lookup("concurrent1.com")
+	assert.True(t, ok)
+}
+
 func TestRateLimiterLockUnlock(t *testing.T) {
 	limiter := New("domain.com", 1)
 	assert.Equal(t, limiter.rate.Limit(), rate.Limit(1))
@@ -35,3 +52,23 @@ func TestRateLimiterRetryAfter(t *testing.T) {
 	after := time.Now()
 	assert.True(t, after.After(expected) || after.Equal(expected))
 }
+
+func TestRateLimiterState(t *testing.T) {
+	limiter := New("state.com", 2)
+	found, ok := Lookup("state.com")
+	assert.True(t, ok)
+	assert.Equal(t, limiter, found)
+	_, ok = Lookup("nope.com")
+	assert.False(t, ok)
+
+	state := limiter.State()
+	assert.Equal(t, 2.0, state.RequestsPerSecond)
+	assert.Equal(t, int64(0), state.Throttled)
+	assert.Nil(t, state.PausedUntil)
+
+	go limiter.retryAfter("1")
+	time.Sleep(100 * time.Millisecond)
+	state = limiter.State()
+	assert.Equal(t, int64(1), state.Throttled)
+	assert.NotNil(t, state.PausedUntil)
+}
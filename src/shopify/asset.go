@@ -2,7 +2,6 @@ package shopify
 
 import (
 	"bytes"
-	"crypto/md5"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -12,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/Shopify/themekit/src/env"
 	"github.com/Shopify/themekit/src/file"
@@ -26,6 +26,10 @@ type Asset struct {
 	ThemeID     int64  `json:"theme_id,omitempty"`
 	Checksum    string `json:"checksum,omitempty"`
 	UpdatedAt   string `json:"updated_at,omitempty"`
+	// Compiled is the key of the asset that shopify generated from this liquid
+	// asset, like assets/app.js from assets/app.js.liquid. It is only set by
+	// GetAllAssets, which leaves the generated asset out of the list.
+	Compiled string `json:"-"`
 }
 
 var (
@@ -35,7 +39,7 @@ var (
 
 // ReadAsset will read a single asset from disk
 func ReadAsset(e *env.Env, filename string) (Asset, error) {
-	return readAsset(e.Directory, filename)
+	return readAsset(e, filename)
 }
 
 // FindAssets will load all assets for paths passed in, this also means that it will
@@ -52,7 +56,7 @@ func FindAssets(e *env.Env, paths ...string) (assets []Asset, err error) {
 	}
 
 	for _, path := range paths {
-		asset, err := readAsset(e.Directory, path)
+		asset, err := readAsset(e, path)
 		if err == ErrAssetIsDir {
 			dirAssets, err := loadAssetsFromDirectory(e, path, filter.Match)
 			if err != nil {
@@ -69,7 +73,11 @@ func FindAssets(e *env.Env, paths ...string) (assets []Asset, err error) {
 	return assets, nil
 }
 
-// Write will write the asset out to the destination directory
+// Write will write the asset out to the destination directory. The contents are
+// written to a temporary file in the same directory first and then renamed into
+// place so that an error or interrupt never leaves a partial file behind. The
+// mode of an existing file is kept and the modification time is set to when the
+// asset was last updated on shopify.
 func (asset Asset) Write(directory string) error {
 	perms, err := os.Stat(directory)
 	if err != nil {
@@ -82,32 +90,68 @@ func (asset Asset) Write(directory string) error {
 		return err
 	}
 
-	file, err := os.Create(filename)
+	contents, err := asset.Contents()
 	if err != nil {
 		return err
 	}
-	defer file.Sync()
-	defer file.Close()
 
-	contents, err := asset.contents()
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".themekit-tmp")
 	if err != nil {
 		return err
 	}
 
-	_, err = file.Write(contents)
-	return err
+	if err := writeSynced(tmp, contents, mode); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if updatedAt, err := time.Parse(time.RFC3339, asset.UpdatedAt); err == nil {
+		os.Chtimes(tmp.Name(), updatedAt, updatedAt)
+	}
+
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// writeSynced will write the data to the file and make sure that it is on disk
+// before the file is closed.
+func writeSynced(file *os.File, data []byte, mode os.FileMode) error {
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	} else if err := file.Chmod(mode); err != nil {
+		file.Close()
+		return err
+	} else if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
 }
 
-func (asset Asset) contents() ([]byte, error) {
+// Contents will return the decoded data of the asset. JSON values are indented
+// so that they are readable when written to disk.
+func (asset Asset) Contents() ([]byte, error) {
 	var data []byte
 	var err error
 	switch {
 	case len(asset.Value) > 0:
 		data = []byte(asset.Value)
 		if filepath.Ext(asset.Key) == ".json" {
+			// values that are not plain json, like settings_data.json with a comment
+			// header, are written out as they are
 			var out bytes.Buffer
-			json.Indent(&out, data, "", "  ")
-			data = out.Bytes()
+			if err := json.Indent(&out, data, "", "  "); err == nil {
+				data = out.Bytes()
+			}
 		}
 	case len(asset.Attachment) > 0:
 		if data, err = base64.StdEncoding.DecodeString(asset.Attachment); err != nil {
@@ -148,7 +192,8 @@ func loadAssetsFromDirectory(e *env.Env, dir string, ignore func(path string) bo
 	return
 }
 
-func readAsset(root, filename string) (asset Asset, err error) {
+func readAsset(e *env.Env, filename string) (asset Asset, err error) {
+	root := e.Directory
 	path := filepath.Join(root, filename)
 
 	key, err := filepath.Rel(root, path)
@@ -177,28 +222,53 @@ func readAsset(root, filename string) (asset Asset, err error) {
 		return Asset{}, fmt.Errorf("readAsset: %s", err)
 	}
 
-	contentType := http.DetectContentType(buffer)
-	if strings.Contains(contentType, "text") {
-		asset.Value = string(buffer)
-		asset.Checksum = calculateTextChecksum(asset.Value, filepath.Ext(asset.Key) == ".json")
+	return NewAssetForEnv(e, asset.Key, buffer), nil
+}
+
+// NewAsset will create an asset from raw file data. Text files are stored as a
+// value and all other files as a base64 encoded attachment, the same way they
+// would be if they were read from disk.
+func NewAsset(key string, data []byte) Asset {
+	return NewAssetForEnv(&env.Env{}, key, data)
+}
+
+// NewAssetForEnv will create an asset from raw file data using the text and binary
+// extensions of the environment. If the environment normalizes line endings then
+// CRLF is replaced with LF in text files before the checksum is calculated. See
+// checksum.go for how checksums are calculated.
+func NewAssetForEnv(e *env.Env, key string, data []byte) Asset {
+	asset := Asset{Key: key}
+	text := isText(e, key, data)
+	if text && e.NormalizeLineEndings {
+		data = bytes.Replace(data, []byte("\r\n"), []byte("\n"), -1)
+	}
+	if text {
+		asset.Value = string(data)
 	} else {
-		asset.Attachment = base64.StdEncoding.EncodeToString(buffer)
-		asset.Checksum = calculateByteArrayChecksum(buffer)
+		asset.Attachment = base64.StdEncoding.EncodeToString(data)
 	}
-	return asset, nil
+	asset.Checksum, _ = calculateChecksum(key, text, data)
+	return asset
 }
 
-func calculateTextChecksum(value string, isJSON bool) (checksum string) {
-	if isJSON {
-		buf := new(bytes.Buffer)
-		json.Compact(buf, []byte(value))
-		return fmt.Sprintf("%x", md5.Sum(buf.Bytes()))
+// isText will return true if a file should be uploaded as text. Extensions set in
+// the environment take precedence over detecting the type from the contents.
+func isText(e *env.Env, key string, data []byte) bool {
+	ext := strings.ToLower(filepath.Ext(key))
+	if hasExtension(e.BinaryExtensions, ext) {
+		return false
+	} else if hasExtension(e.TextExtensions, ext) {
+		return true
 	}
-	return fmt.Sprintf("%x", md5.Sum([]byte(value)))
+	return strings.Contains(http.DetectContentType(data), "text")
 }
 
-func calculateByteArrayChecksum(value []byte) (checksum string) {
-	hash := md5.New()
-	hash.Write(value)
-	return fmt.Sprintf("%x", hash.Sum(nil))
+func hasExtension(extensions []string, ext string) bool {
+	for _, extension := range extensions {
+		extension = strings.ToLower(strings.TrimSpace(extension))
+		if extension != "" && "."+strings.TrimPrefix(extension, ".") == ext {
+			return true
+		}
+	}
+	return false
 }
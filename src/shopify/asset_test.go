@@ -2,10 +2,13 @@ package shopify
 
 import (
 	"encoding/base64"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -66,6 +69,37 @@ func TestAsset_Write(t *testing.T) {
 	os.RemoveAll(testDir)
 }
 
+func TestAsset_WriteAtomic(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "write")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "assets", "app.js")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	ioutil.WriteFile(path, []byte("local work"), 0600)
+
+	err := Asset{Key: "assets/app.js", Attachment: "this is bad content"}.Write(dir)
+	assert.NotNil(t, err)
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "local work", string(data))
+
+	err = Asset{Key: "assets/app.js", Value: "remote work", UpdatedAt: "2019-07-09T14:33:21-04:00"}.Write(dir)
+	assert.Nil(t, err)
+	data, _ = ioutil.ReadFile(path)
+	assert.Equal(t, "remote work", string(data))
+	info, _ := os.Stat(path)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+	assert.True(t, info.ModTime().Equal(time.Date(2019, 7, 9, 18, 33, 21, 0, time.UTC)))
+
+	err = Asset{Key: "config/settings_data.json", Value: "/* comment */\n{\"current\":{}}"}.Write(dir)
+	assert.Nil(t, err)
+	data, _ = ioutil.ReadFile(filepath.Join(dir, "config", "settings_data.json"))
+	assert.Equal(t, "/* comment */\n{\"current\":{}}", string(data))
+
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "assets"))
+	assert.Equal(t, 1, len(files))
+}
+
 func TestAsset_Contents(t *testing.T) {
 	testcases := []struct {
 		asset  Asset
@@ -79,7 +113,7 @@ func TestAsset_Contents(t *testing.T) {
 	}
 
 	for _, testcase := range testcases {
-		data, err := testcase.asset.contents()
+		data, err := testcase.asset.Contents()
 		if testcase.err == "" {
 			assert.Nil(t, err)
 			assert.Equal(t, testcase.length, len(data))
@@ -89,6 +123,52 @@ func TestAsset_Contents(t *testing.T) {
 	}
 }
 
+func TestNewAsset(t *testing.T) {
+	asset := NewAsset("assets/application.js", []byte("this is js content"))
+	assert.Equal(t, Asset{Key: "assets/application.js", Value: "this is js content", Checksum: "e7aafdd5b05060f8ff35457db4b2d4f8"}, asset)
+
+	asset = NewAsset("assets/app.json", []byte("{\"testing\" : \"data\"}"))
+	assert.Equal(t, "31409bedd9f5852166c0a4a9b874f1a7", asset.Checksum)
+
+	asset = NewAsset("assets/image.png", []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a})
+	assert.Equal(t, "", asset.Value)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a}), asset.Attachment)
+}
+
+func TestNewAssetForEnv(t *testing.T) {
+	svg := []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>")
+	e := &env.Env{TextExtensions: []string{"svg"}, BinaryExtensions: []string{".JS"}}
+
+	asset := NewAssetForEnv(e, "assets/icon.svg", svg)
+	assert.Equal(t, string(svg), asset.Value)
+	assert.Equal(t, "", asset.Attachment)
+
+	asset = NewAssetForEnv(e, "assets/app.js", []byte("this is js content"))
+	assert.Equal(t, "", asset.Value)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("this is js content")), asset.Attachment)
+
+	utf16 := []byte{0xff, 0xfe, '<', 0}
+	assert.Equal(t, string(utf16), NewAsset("assets/icon.svg", utf16).Value)
+	asset = NewAssetForEnv(&env.Env{BinaryExtensions: []string{"svg"}}, "assets/icon.svg", utf16)
+	assert.Equal(t, "", asset.Value)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(utf16), asset.Attachment)
+
+	crlf := NewAssetForEnv(&env.Env{NormalizeLineEndings: true}, "layout/theme.liquid", []byte("<html>\r\n</html>\r\n"))
+	lf := NewAsset("layout/theme.liquid", []byte("<html>\n</html>\n"))
+	assert.Equal(t, lf, crlf)
+	assert.NotEqual(t, lf.Checksum, NewAsset("layout/theme.liquid", []byte("<html>\r\n</html>\r\n")).Checksum)
+
+	image := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a}
+	assert.Equal(t, base64.StdEncoding.EncodeToString(image), NewAssetForEnv(&env.Env{NormalizeLineEndings: true}, "assets/image.png", image).Attachment)
+}
+
+func TestHasExtension(t *testing.T) {
+	assert.True(t, hasExtension([]string{"svg"}, ".svg"))
+	assert.True(t, hasExtension([]string{" .SVG "}, ".svg"))
+	assert.False(t, hasExtension([]string{"svg", ""}, ".js"))
+	assert.False(t, hasExtension([]string{""}, ""))
+}
+
 func TestLoadAssetsFromDirectory(t *testing.T) {
 	ignoreNone := func(path string) bool { return strings.Contains(path, ".gitkeep") }
 	selectOne := func(path string) bool { return path != "assets/application.js" }
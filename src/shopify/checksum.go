@@ -0,0 +1,54 @@
+package shopify
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/Shopify/themekit/src/env"
+)
+
+// Local checksums are calculated the same way that shopify calculates the
+// checksums in the asset list so that files can be compared without downloading
+// them. The strategy depends on the type of the asset:
+//
+//   - binary files are the md5 of their contents.
+//   - JSON files are the md5 of the compacted JSON. Shopify stores JSON compacted
+//     while files are written to disk indented, so whitespace is not a change.
+//   - JSON that cannot be parsed, like settings_data.json with a comment header,
+//     is the md5 of its contents as it is, the same way shopify stores it.
+//   - all other text files are the md5 of their contents, after CRLF has been
+//     replaced with LF if normalize_line_endings is set.
+//
+// Compiled assets, like assets/app.js that shopify generates from
+// assets/app.js.liquid, are never compared because they only exist on shopify.
+// GetAllAssets leaves them out and sets Compiled on their liquid source.
+const (
+	ChecksumBinary  = "binary"
+	ChecksumText    = "text"
+	ChecksumJSON    = "compacted json"
+	ChecksumRawJSON = "json that could not be parsed, as it is"
+)
+
+// ChecksumStrategy will return how the checksum of a local file is calculated
+func ChecksumStrategy(e *env.Env, key string, data []byte) string {
+	_, strategy := calculateChecksum(key, isText(e, key, data), data)
+	return strategy
+}
+
+// calculateChecksum will return the checksum of the data of an asset and the
+// strategy that was used to calculate it.
+func calculateChecksum(key string, text bool, data []byte) (string, string) {
+	if !text {
+		return fmt.Sprintf("%x", md5.Sum(data)), ChecksumBinary
+	} else if filepath.Ext(key) != ".json" {
+		return fmt.Sprintf("%x", md5.Sum(data)), ChecksumText
+	}
+	buf := new(bytes.Buffer)
+	if err := json.Compact(buf, data); err != nil {
+		return fmt.Sprintf("%x", md5.Sum(data)), ChecksumRawJSON
+	}
+	return fmt.Sprintf("%x", md5.Sum(buf.Bytes())), ChecksumJSON
+}
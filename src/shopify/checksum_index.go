@@ -0,0 +1,186 @@
+package shopify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/file"
+)
+
+// checksumIndexVersion is changed whenever the way checksums are calculated
+// changes so that old indexes are not used.
+const checksumIndexVersion = 2
+
+// racyInterval is how recently a file can have been modified and still be cached.
+// A file that is changed again within the resolution of its modification time
+// would keep the same size and mtime, so its checksum is not trusted.
+var racyInterval = 2 * time.Second
+
+// ChecksumIndex caches the checksums of local files keyed by their path, size and
+// modification time so that files that have not changed are not read again.
+type ChecksumIndex struct {
+	path    string
+	mu      sync.Mutex
+	dirty   bool
+	Version int                   `json:"version"`
+	Files   map[string]indexEntry `json:"files"`
+}
+
+type indexEntry struct {
+	Size     int64  `json:"size"`
+	ModTime  int64  `json:"mtime"`
+	Checksum string `json:"checksum"`
+	Settings string `json:"settings,omitempty"`
+}
+
+// LoadChecksumIndex will load the index saved at path. If there is no index, or it
+// cannot be read, an empty index is returned and a new one will be saved.
+func LoadChecksumIndex(path string) *ChecksumIndex {
+	index := &ChecksumIndex{path: path, Version: checksumIndexVersion, Files: map[string]indexEntry{}}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return index
+	}
+	saved := ChecksumIndex{}
+	if err := json.Unmarshal(data, &saved); err == nil && saved.Version == checksumIndexVersion && saved.Files != nil {
+		index.Files = saved.Files
+	}
+	return index
+}
+
+// Checksum will return the checksum of a file in the project, reading the file
+// only if it changed since it was last indexed. A nil index always reads the file.
+func (index *ChecksumIndex) Checksum(e *env.Env, key string, info os.FileInfo) (string, error) {
+	settings := checksumSettings(e)
+	if index != nil {
+		index.mu.Lock()
+		entry, ok := index.Files[key]
+		index.mu.Unlock()
+		if ok && entry.Size == info.Size() && entry.ModTime == info.ModTime().UnixNano() && entry.Settings == settings {
+			return entry.Checksum, nil
+		}
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(e.Directory, filepath.FromSlash(key)))
+	if err != nil {
+		return "", fmt.Errorf("readAsset: %s", err)
+	}
+	checksum := NewAssetForEnv(e, key, data).Checksum
+
+	if index != nil && time.Since(info.ModTime()) > racyInterval {
+		index.mu.Lock()
+		index.Files[key] = indexEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Checksum: checksum, Settings: settings}
+		index.dirty = true
+		index.mu.Unlock()
+	}
+	return checksum, nil
+}
+
+// checksumSettings will describe the settings of an environment that change the
+// checksum of a file, environments with different settings can share the index.
+func checksumSettings(e *env.Env) string {
+	if len(e.TextExtensions) == 0 && len(e.BinaryExtensions) == 0 && !e.NormalizeLineEndings {
+		return ""
+	}
+	return fmt.Sprintf("text=%s;binary=%s;lf=%v", strings.Join(e.TextExtensions, ","), strings.Join(e.BinaryExtensions, ","), e.NormalizeLineEndings)
+}
+
+// prune will remove the files that were not found from the index
+func (index *ChecksumIndex) prune(found map[string]bool) {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	for key := range index.Files {
+		if !found[key] {
+			delete(index.Files, key)
+			index.dirty = true
+		}
+	}
+}
+
+// Save will write the index to disk if it has changed
+func (index *ChecksumIndex) Save() error {
+	if index == nil || index.path == "" {
+		return nil
+	}
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if !index.dirty {
+		return nil
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	} else if err := os.MkdirAll(filepath.Dir(index.path), 0755); err != nil {
+		return err
+	}
+	// every environment of a project shares the index so it is renamed into place
+	tmp, err := ioutil.TempFile(filepath.Dir(index.path), filepath.Base(index.path))
+	if err != nil {
+		return err
+	} else if err := writeSynced(tmp, data, 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	} else if err := os.Rename(tmp.Name(), index.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	index.dirty = false
+	return nil
+}
+
+// FindChecksums will find the same files as FindAssets but only load their keys
+// and checksums. The contents of the files are not kept in memory, they can be
+// loaded with ReadAsset for the files that need to be uploaded. Checksums are
+// looked up in the index first, the index has to be saved afterwards with Save.
+func FindChecksums(e *env.Env, index *ChecksumIndex, paths ...string) ([]Asset, error) {
+	filter, err := file.NewFilter(e.Directory, e.IgnoredFiles, e.Ignores)
+	if err != nil {
+		return []Asset{}, err
+	}
+
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+
+	assets := []Asset{}
+	found := map[string]bool{}
+	for _, path := range paths {
+		err := filepath.Walk(filepath.Join(e.Directory, path), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return fmt.Errorf("readAsset: %s", err)
+			} else if info.IsDir() {
+				return nil
+			}
+			key, err := filepath.Rel(e.Directory, path)
+			if err != nil {
+				return err
+			}
+			key = filepath.ToSlash(key)
+			if filter.Match(key) {
+				return nil
+			}
+			checksum, err := index.Checksum(e, key, info)
+			if err != nil {
+				return err
+			}
+			found[key] = true
+			assets = append(assets, Asset{Key: key, Checksum: checksum})
+			return nil
+		})
+		if err != nil {
+			return []Asset{}, err
+		}
+	}
+
+	if index != nil && len(paths) == 1 && paths[0] == "" {
+		index.prune(found)
+	}
+	return assets, nil
+}
@@ -0,0 +1,123 @@
+package shopify
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Shopify/themekit/src/env"
+)
+
+func TestChecksumIndex(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "index")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "assets", "app.js")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	ioutil.WriteFile(path, []byte("var a = 1;"), 0644)
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(path, past, past)
+	info, _ := os.Stat(path)
+
+	e := &env.Env{Directory: dir}
+	indexPath := filepath.Join(dir, ".themekit", "checksums.json")
+	index := LoadChecksumIndex(indexPath)
+	checksum, err := index.Checksum(e, "assets/app.js", info)
+	assert.Nil(t, err)
+	assert.Equal(t, NewAsset("assets/app.js", []byte("var a = 1;")).Checksum, checksum)
+	assert.Nil(t, index.Save())
+
+	// a cached checksum is used as long as the size and mtime match
+	index = LoadChecksumIndex(indexPath)
+	index.Files["assets/app.js"] = indexEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Checksum: "cached"}
+	checksum, _ = index.Checksum(e, "assets/app.js", info)
+	assert.Equal(t, "cached", checksum)
+
+	ioutil.WriteFile(path, []byte("var a = 2;"), 0644)
+	info, _ = os.Stat(path)
+	checksum, _ = index.Checksum(e, "assets/app.js", info)
+	assert.Equal(t, NewAsset("assets/app.js", []byte("var a = 2;")).Checksum, checksum)
+	// the file was just modified so it is not cached yet
+	assert.Equal(t, "cached", index.Files["assets/app.js"].Checksum)
+
+	// a checksum cached with other settings is not used
+	os.Chtimes(path, past, past)
+	info, _ = os.Stat(path)
+	checksum, _ = index.Checksum(e, "assets/app.js", info)
+	assert.Equal(t, "", index.Files["assets/app.js"].Settings)
+	checksum, _ = index.Checksum(&env.Env{Directory: dir, BinaryExtensions: []string{"js"}}, "assets/app.js", info)
+	assert.Equal(t, NewAssetForEnv(&env.Env{BinaryExtensions: []string{"js"}}, "assets/app.js", []byte("var a = 2;")).Checksum, checksum)
+	assert.Equal(t, "text=;binary=js;lf=false", index.Files["assets/app.js"].Settings)
+
+	var nilIndex *ChecksumIndex
+	checksum, err = nilIndex.Checksum(e, "assets/app.js", info)
+	assert.Nil(t, err)
+	assert.Equal(t, NewAsset("assets/app.js", []byte("var a = 2;")).Checksum, checksum)
+	assert.Nil(t, nilIndex.Save())
+
+	_, err = index.Checksum(e, "assets/nope.js", info)
+	assert.NotNil(t, err)
+
+	ioutil.WriteFile(indexPath, []byte("not json"), 0644)
+	assert.Equal(t, 0, len(LoadChecksumIndex(indexPath).Files))
+}
+
+func TestFindChecksums(t *testing.T) {
+	goodEnv := &env.Env{Directory: filepath.Join("_testdata", "project")}
+	badEnv := &env.Env{Directory: "nope"}
+
+	testcases := []struct {
+		e      *env.Env
+		inputs []string
+		err    string
+	}{
+		{e: goodEnv, inputs: []string{filepath.Join("assets", "application.js")}},
+		{e: goodEnv},
+		{e: badEnv, err: "readAsset: "},
+		{e: goodEnv, inputs: []string{"assets", "config/settings_data.json"}},
+		{e: goodEnv, inputs: []string{"snippets/nope.txt"}, err: "readAsset: "},
+	}
+
+	for _, testcase := range testcases {
+		checksums, err := FindChecksums(testcase.e, nil, testcase.inputs...)
+		if testcase.err != "" {
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), testcase.err)
+			}
+			continue
+		}
+		assert.Nil(t, err)
+		assets, _ := FindAssets(testcase.e, testcase.inputs...)
+		if assert.Equal(t, len(assets), len(checksums)) {
+			for i, asset := range assets {
+				assert.Equal(t, asset.Key, checksums[i].Key)
+				assert.Equal(t, asset.Checksum, checksums[i].Checksum)
+				assert.Equal(t, "", checksums[i].Value+checksums[i].Attachment)
+			}
+		}
+	}
+}
+
+func TestFindChecksumsPrunesIndex(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "index")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "assets"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "assets", "app.js"), []byte(""), 0644)
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(dir, "assets", "app.js"), past, past)
+
+	indexPath := filepath.Join(dir, ".themekit", "checksums.json")
+	index := LoadChecksumIndex(indexPath)
+	index.Files["assets/deleted.js"] = indexEntry{Checksum: "old"}
+	index.dirty = true
+	_, err := FindChecksums(&env.Env{Directory: dir}, index)
+	assert.Nil(t, err)
+	assert.Nil(t, index.Save())
+
+	saved := LoadChecksumIndex(indexPath)
+	assert.Equal(t, 1, len(saved.Files))
+	assert.Equal(t, "d41d8cd98f00b204e9800998ecf8427e", saved.Files["assets/app.js"].Checksum)
+}
@@ -0,0 +1,34 @@
+package shopify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Shopify/themekit/src/env"
+)
+
+func TestCalculateChecksum(t *testing.T) {
+	testcases := []struct {
+		key, data, checksum, strategy string
+		text                          bool
+	}{
+		{key: "assets/app.js", data: "this is js content", text: true, checksum: "e7aafdd5b05060f8ff35457db4b2d4f8", strategy: ChecksumText},
+		{key: "assets/app.png", data: "this is js content", checksum: "e7aafdd5b05060f8ff35457db4b2d4f8", strategy: ChecksumBinary},
+		{key: "templates/index.json", data: "{\"a\": 1}", text: true, checksum: "bb6cb5c68df4652941caf652a366f2d8", strategy: ChecksumJSON},
+		{key: "templates/index.json", data: "{\n  \"a\": 1\n}\n", text: true, checksum: "bb6cb5c68df4652941caf652a366f2d8", strategy: ChecksumJSON},
+		{key: "config/settings_data.json", data: "/* header */\n{\"a\": 1}", text: true, checksum: "472d0f57e34bfff0d6bf67b0e4d9ddf8", strategy: ChecksumRawJSON},
+	}
+
+	for _, testcase := range testcases {
+		checksum, strategy := calculateChecksum(testcase.key, testcase.text, []byte(testcase.data))
+		assert.Equal(t, testcase.checksum, checksum, testcase.data)
+		assert.Equal(t, testcase.strategy, strategy, testcase.data)
+	}
+}
+
+func TestChecksumStrategy(t *testing.T) {
+	assert.Equal(t, ChecksumText, ChecksumStrategy(&env.Env{}, "assets/icon.svg", []byte("<svg></svg>")))
+	assert.Equal(t, ChecksumBinary, ChecksumStrategy(&env.Env{BinaryExtensions: []string{"svg"}}, "assets/icon.svg", []byte("<svg></svg>")))
+	assert.Equal(t, ChecksumJSON, ChecksumStrategy(&env.Env{}, "locales/en.default.json", []byte("{}")))
+}
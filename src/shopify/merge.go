@@ -0,0 +1,147 @@
+package shopify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MergeJSON will deep merge the JSON of a local asset with the JSON of the same
+// asset on shopify, using base as the version that both were changed from, the
+// version that was last deployed or downloaded. Values that were only changed on
+// one side keep that change. Values that were changed on both sides keep the
+// remote value so that changes made in the theme editor are preserved, while keys
+// that only exist locally are added. Lists of plain values that were changed on
+// both sides keep the remote order and have any new local values appended.
+//
+// If base has no value, or cannot be parsed, every value that exists remotely
+// wins because it is not known which side changed it.
+func MergeJSON(base, local, remote Asset) (Asset, error) {
+	localHeader, localBody := splitJSONComment(local.Value)
+	remoteHeader, remoteBody := splitJSONComment(remote.Value)
+	_, baseBody := splitJSONComment(base.Value)
+
+	var localData, remoteData, baseData interface{}
+	if err := json.Unmarshal([]byte(localBody), &localData); err != nil {
+		return Asset{}, fmt.Errorf("could not parse local %s: %s", local.Key, err)
+	}
+	if err := json.Unmarshal([]byte(remoteBody), &remoteData); err != nil {
+		return Asset{}, fmt.Errorf("could not parse remote %s: %s", remote.Key, err)
+	}
+	if err := json.Unmarshal([]byte(baseBody), &baseData); err != nil {
+		baseData = noBase{}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(mergeValues(baseData, localData, remoteData)); err != nil {
+		return Asset{}, err
+	}
+
+	header := remoteHeader
+	if header == "" {
+		header = localHeader
+	}
+	value := header + buf.String()
+	checksum, _ := calculateChecksum(local.Key, true, []byte(value))
+	return Asset{Key: local.Key, Value: value, Checksum: checksum}, nil
+}
+
+// noBase is the base of a value that did not exist in the base version, or of
+// every value if there is no base version.
+type noBase struct{}
+
+func mergeValues(base, local, remote interface{}) interface{} {
+	if _, ok := base.(noBase); !ok {
+		if reflect.DeepEqual(remote, base) {
+			return local
+		} else if reflect.DeepEqual(local, base) {
+			return remote
+		}
+	}
+
+	switch remoteValue := remote.(type) {
+	case map[string]interface{}:
+		localValue, ok := local.(map[string]interface{})
+		if !ok {
+			return remote
+		}
+		baseValue, _ := base.(map[string]interface{})
+		merged := map[string]interface{}{}
+		for key, value := range localValue {
+			baseChild, inBase := baseValue[key]
+			if _, inRemote := remoteValue[key]; !inRemote && inBase && reflect.DeepEqual(value, baseChild) {
+				// removed in the theme editor and not changed locally
+				continue
+			}
+			merged[key] = value
+		}
+		for key, value := range remoteValue {
+			baseChild, inBase := baseValue[key]
+			if !inBase {
+				baseChild = noBase{}
+			}
+			if localChild, ok := localValue[key]; ok {
+				merged[key] = mergeValues(baseChild, localChild, value)
+			} else if !inBase || !reflect.DeepEqual(value, baseChild) {
+				// the key was not removed locally, or was also changed in the theme editor
+				merged[key] = value
+			}
+		}
+		return merged
+	case []interface{}:
+		localValue, ok := local.([]interface{})
+		if !ok || !isScalarList(remoteValue) || !isScalarList(localValue) {
+			return remote
+		}
+		merged := append([]interface{}{}, remoteValue...)
+		for _, value := range localValue {
+			if !containsValue(remoteValue, value) {
+				merged = append(merged, value)
+			}
+		}
+		return merged
+	}
+	return remote
+}
+
+func isScalarList(list []interface{}) bool {
+	for _, value := range list {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return false
+		}
+	}
+	return true
+}
+
+func containsValue(list []interface{}, value interface{}) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitJSONComment will separate the comment that shopify adds to the top of
+// generated JSON files from the JSON itself.
+func splitJSONComment(value string) (string, string) {
+	trimmed := strings.TrimLeft(value, " \t\r\n")
+	if !strings.HasPrefix(trimmed, "/*") {
+		return "", value
+	}
+	end := strings.Index(trimmed, "*/")
+	if end < 0 {
+		return "", value
+	}
+	end += len("*/")
+	for end < len(trimmed) && (trimmed[end] == '\n' || trimmed[end] == '\r') {
+		end++
+	}
+	return trimmed[:end], trimmed[end:]
+}
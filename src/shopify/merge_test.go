@@ -0,0 +1,101 @@
+package shopify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeJSON(t *testing.T) {
+	local := Asset{Key: "templates/index.json", Value: `{
+  "sections": {
+    "hero": {"type": "hero", "settings": {"title": "Local title", "subtitle": "New subtitle"}},
+    "faq": {"type": "faq"}
+  },
+  "order": ["hero", "faq"]
+}`}
+	remote := Asset{Key: "templates/index.json", Value: `/*
+ * This file is auto-generated
+ */
+{
+  "sections": {
+    "hero": {"type": "hero", "settings": {"title": "Merchant <b>title</b>"}},
+    "banner": {"type": "banner"}
+  },
+  "order": ["banner", "hero"]
+}`}
+
+	merged, err := MergeJSON(Asset{}, local, remote)
+	assert.Nil(t, err)
+	assert.Equal(t, "templates/index.json", merged.Key)
+	assert.Equal(t, `/*
+ * This file is auto-generated
+ */
+{
+  "order": [
+    "banner",
+    "hero",
+    "faq"
+  ],
+  "sections": {
+    "banner": {
+      "type": "banner"
+    },
+    "faq": {
+      "type": "faq"
+    },
+    "hero": {
+      "settings": {
+        "subtitle": "New subtitle",
+        "title": "Merchant <b>title</b>"
+      },
+      "type": "hero"
+    }
+  }
+}
+`, merged.Value)
+	assert.NotEqual(t, "", merged.Checksum)
+
+	merged, err = MergeJSON(Asset{}, Asset{Key: "config/settings_data.json", Value: `{"a": [{"b": 1}], "c": 1}`}, Asset{Value: `{"a": [{"b": 2}], "c": {"d": 1}}`})
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n  \"a\": [\n    {\n      \"b\": 2\n    }\n  ],\n  \"c\": {\n    \"d\": 1\n  }\n}\n", merged.Value)
+
+	_, err = MergeJSON(Asset{}, Asset{Key: "config/settings_data.json", Value: `{`}, remote)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not parse local config/settings_data.json")
+	}
+
+	_, err = MergeJSON(Asset{}, local, Asset{Key: "config/settings_data.json", Value: `/* unterminated`})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not parse remote config/settings_data.json")
+	}
+}
+
+func TestMergeJSONWithBase(t *testing.T) {
+	base := Asset{Value: `{"current": {"color": "red", "font": "sans", "logo": "a.png", "banner": true, "order": ["a", "b"]}}`}
+	local := Asset{Key: "config/settings_data.json", Value: `{"current": {"color": "blue", "font": "sans", "banner": true, "order": ["a", "b", "c"], "new": 1}}`}
+	remote := Asset{Value: `{"current": {"color": "red", "font": "serif", "logo": "a.png", "order": ["b", "a"]}}`}
+
+	merged, err := MergeJSON(base, local, remote)
+	assert.Nil(t, err)
+	// color changed locally, font changed in the editor, logo removed locally, banner
+	// removed in the editor and order changed on both sides
+	assert.Equal(t, `{
+  "current": {
+    "color": "blue",
+    "font": "serif",
+    "new": 1,
+    "order": [
+      "b",
+      "a",
+      "c"
+    ]
+  }
+}
+`, merged.Value)
+
+	merged, err = MergeJSON(Asset{Value: `{`}, local, remote)
+	assert.Nil(t, err)
+	assert.Contains(t, merged.Value, `"color": "red"`)
+	assert.Contains(t, merged.Value, `"logo": "a.png"`)
+}
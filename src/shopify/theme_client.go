@@ -44,6 +44,7 @@ type Theme struct {
 	Role        string `json:"role,omitempty"`
 	Previewable bool   `json:"previewable,omitempty"`
 	Processing  bool   `json:"processing,omitempty"`
+	Src         string `json:"src,omitempty"`
 }
 
 // Shop information for the domain your are currently working on
@@ -152,8 +153,25 @@ func (c *Client) CreateNewTheme(name string) (theme Theme, err error) {
 	if name == "" {
 		return Theme{}, ErrThemeNameRequired
 	}
+	return c.createTheme(Theme{Name: name})
+}
 
-	resp, err := c.http.Post(APIPath+"themes.json", map[string]interface{}{"theme": Theme{Name: name}}, nil)
+// CreateThemeFromSrc will create a new theme on your shopify store from a zip file
+// at a publicly reachable url. The role is optional and the theme is unpublished
+// when it is empty. Shopify imports the zip in the background so the theme will
+// be processing until the import is done. The theme id on this theme client is
+// set to the one recently created.
+func (c *Client) CreateThemeFromSrc(name, src, role string) (Theme, error) {
+	if name == "" {
+		return Theme{}, ErrThemeNameRequired
+	} else if src == "" {
+		return Theme{}, ErrZipPathRequired
+	}
+	return c.createTheme(Theme{Name: name, Src: src, Role: role})
+}
+
+func (c *Client) createTheme(theme Theme) (Theme, error) {
+	resp, err := c.http.Post(APIPath+"themes.json", map[string]interface{}{"theme": theme}, nil)
 	if err != nil {
 		return Theme{}, err
 	}
@@ -224,7 +242,9 @@ func (c Client) PublishTheme() error {
 // GetAllAssets will return a slice of remote assets from the shopify servers. The
 // assets are sorted and any ignored files based on your config are filtered out.
 // The assets returned will not have any data, only ID and filenames. This is because
-// fetching all the assets at one time is not a good idea.
+// fetching all the assets at one time is not a good idea. Assets that shopify
+// compiled from a liquid asset are left out and their key is set as Compiled on
+// the liquid asset.
 func (c Client) GetAllAssets() ([]Asset, error) {
 	resp, err := c.http.Get(c.assetPath(map[string]string{"fields": "key,checksum"}), nil)
 	if err != nil {
@@ -238,12 +258,21 @@ func (c Client) GetAllAssets() ([]Asset, error) {
 		return []Asset{}, err
 	}
 
+	keys := map[string]bool{}
+	for _, asset := range r.Assets {
+		keys[asset.Key] = true
+	}
+
 	filteredAssets := []Asset{}
 	sort.Slice(r.Assets, func(i, j int) bool { return r.Assets[i].Key < r.Assets[j].Key })
-	for index, asset := range r.Assets {
-		if !c.filter.Match(asset.Key) && (index == len(r.Assets)-1 || r.Assets[index+1].Key != asset.Key+".liquid") {
-			filteredAssets = append(filteredAssets, asset)
+	for _, asset := range r.Assets {
+		if c.filter.Match(asset.Key) || keys[asset.Key+".liquid"] {
+			continue
+		}
+		if compiled := strings.TrimSuffix(asset.Key, ".liquid"); compiled != asset.Key && keys[compiled] {
+			asset.Compiled = compiled
 		}
+		filteredAssets = append(filteredAssets, asset)
 	}
 
 	return filteredAssets, nil
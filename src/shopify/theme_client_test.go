@@ -156,6 +156,49 @@ func TestThemeClient_CreateNewTheme(t *testing.T) {
 	}
 }
 
+func TestThemeClient_CreateThemeFromSrc(t *testing.T) {
+	src := "https://example.com/theme.zip"
+	testcases := []struct {
+		name, src, role    string
+		resp, resperr, err string
+	}{
+		{src: src, err: ErrThemeNameRequired.Error()},
+		{name: "my theme", err: ErrZipPathRequired.Error()},
+		{name: "my theme", src: src, resp: `{"errors": {"src": ["is not a valid zip"]}}`, err: "is not a valid zip"},
+		{name: "my theme", src: src, resperr: "(Client.Timeout exceeded while awaiting headers)", err: "(Client.Timeout exceeded while awaiting headers)"},
+		{name: "my theme", src: src, resp: `{"theme":{"id": 123456,"name":"timberland","role":"unpublished","processing":true}}`},
+		{name: "my theme", src: src, role: "main", resp: `{"theme":{"id": 123456,"name":"timberland","role":"main","processing":true}}`},
+	}
+
+	for _, testcase := range testcases {
+		client, _ := NewClient(&env.Env{})
+		m := new(mocks.HttpAdapter)
+		client.http = m
+		query := map[string]interface{}{"theme": Theme{Name: testcase.name, Src: testcase.src, Role: testcase.role}}
+
+		if testcase.resp != "" {
+			m.On("Post", APIPath+"themes.json", query, NoHeaders).Return(jsonResponse(testcase.resp, 200), nil)
+		} else if testcase.resperr != "" {
+			m.On("Post", APIPath+"themes.json", query, NoHeaders).Return(nil, errors.New(testcase.resperr))
+		}
+
+		theme, err := client.CreateThemeFromSrc(testcase.name, testcase.src, testcase.role)
+
+		if testcase.err == "" {
+			assert.Nil(t, err)
+			assert.Equal(t, int64(123456), theme.ID)
+			assert.True(t, theme.Processing)
+			assert.Equal(t, "123456", client.themeID)
+		} else if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), testcase.err)
+		}
+
+		if testcase.resp != "" || testcase.resperr != "" {
+			m.AssertExpectations(t)
+		}
+	}
+}
+
 func TestThemeClient_GetInfo(t *testing.T) {
 	testcases := []struct {
 		themeID, resp, resperr, err string
@@ -282,11 +325,15 @@ func TestThemeClient_GetAllAssets(t *testing.T) {
 	}{
 		{
 			input:    `{"assets":[{"key":"templates/foo.json.liquid"},{"key":"templates/foo.json"}]}`,
-			expected: []Asset{{Key: "templates/foo.json.liquid"}},
+			expected: []Asset{{Key: "templates/foo.json.liquid", Compiled: "templates/foo.json"}},
 		},
 		{
 			input:    `{"assets":[{"key":"templates/foo.json"},{"key":"templates/foo.json.liquid"}]}`,
-			expected: []Asset{{Key: "templates/foo.json.liquid"}},
+			expected: []Asset{{Key: "templates/foo.json.liquid", Compiled: "templates/foo.json"}},
+		},
+		{
+			input:    `{"assets":[{"key":"assets/app.js"},{"key":"assets/app.js.gz"},{"key":"assets/app.js.liquid"}]}`,
+			expected: []Asset{{Key: "assets/app.js.gz"}, {Key: "assets/app.js.liquid", Compiled: "assets/app.js"}},
 		},
 		{
 			input:    `{"assets":[{"key":"templates/ignore.html.liquid"},{"key":"templates/other.liquid"}]}`,
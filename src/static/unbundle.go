@@ -2,32 +2,112 @@ package static
 
 import (
 	"archive/zip"
-	"io"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
 
 	"github.com/Shopify/themekit/src/cmdutil"
 	"github.com/Shopify/themekit/src/colors"
 )
 
-var zipData string
+// DefaultStarter is the name of the starter that is generated from theme-template
+const DefaultStarter = "default"
 
-// Register will set the zip data that can be decompressed
+// Starter files can use these delimiters to insert variables like {{% .Name %}}.
+// They are different from the default template delimiters so that they do not
+// conflict with liquid.
+const (
+	leftDelim  = "{{%"
+	rightDelim = "%}}"
+)
+
+var starters = map[string]string{}
+
+// StarterVars are the variables that can be used in the contents of starter files
+type StarterVars struct {
+	Name    string
+	Store   string
+	Domain  string
+	ThemeID string
+}
+
+// Register will set the zip data of the default starter
 func Register(data string) {
-	zipData = data
+	RegisterStarter(DefaultStarter, data)
+}
+
+// RegisterStarter will add zip data to the starters that can be unbundled by name
+func RegisterStarter(name, data string) {
+	starters[name] = data
+}
+
+// HasStarter will return true if a starter with the name has been registered
+func HasStarter(name string) bool {
+	_, ok := starters[name]
+	return ok
 }
 
-// Unbundle will saftely put all files in place without overwriting files that
-// already exist
+// Starters will return the names of all the registered starters
+func Starters() []string {
+	names := []string{}
+	for name := range starters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Unbundle will saftely put all files of the default starter in place without
+// overwriting files that already exist
 func Unbundle(ctx *cmdutil.Ctx) error {
-	files, err := getZipContents(zipData)
+	return UnbundleStarter(ctx, DefaultStarter)
+}
+
+// UnbundleStarter will saftely put all files of a registered starter in place
+// without overwriting files that already exist
+func UnbundleStarter(ctx *cmdutil.Ctx, name string) error {
+	data, ok := starters[name]
+	if !ok {
+		return fmt.Errorf("unknown starter %s, available starters are %s", name, strings.Join(Starters(), ", "))
+	}
+	files, err := getZipContents(data)
 	if err != nil {
 		return err
 	}
 	return createDirs(ctx, files)
 }
 
+// Expand will substitute the starter variables in the contents of a text file.
+// Binary files and files without variables are returned as they are.
+func Expand(ctx *cmdutil.Ctx, name string, data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(leftDelim)) || !strings.Contains(http.DetectContentType(data), "text") {
+		return data, nil
+	}
+
+	tmpl, err := template.New(name).Delims(leftDelim, rightDelim).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not expand %s: %s", name, err)
+	}
+
+	vars := StarterVars{Name: ctx.Flags.Name, Store: ctx.Shop.Name}
+	if ctx.Env != nil {
+		vars.Domain = ctx.Env.Domain
+		vars.ThemeID = ctx.Env.ThemeID
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return nil, fmt.Errorf("could not expand %s: %s", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
 func getZipContents(data string) (map[string]map[string]*zip.File, error) {
 	files := map[string]map[string]*zip.File{}
 	zipReader, err := zip.NewReader(strings.NewReader(data), int64(len(data)))
@@ -56,7 +136,6 @@ func createDirs(ctx *cmdutil.Ctx, dirFiles map[string]map[string]*zip.File) erro
 			ctx.Log.Printf("%s %s.\n", colors.Blue("Exists"), dir)
 		}
 		for path, file := range files {
-			path = filepath.Join(ctx.Flags.Directory, path)
 			if err := writeFile(ctx, path, file); err != nil {
 				return err
 			}
@@ -65,28 +144,31 @@ func createDirs(ctx *cmdutil.Ctx, dirFiles map[string]map[string]*zip.File) erro
 	return nil
 }
 
-func writeFile(ctx *cmdutil.Ctx, path string, zfile *zip.File) error {
+func writeFile(ctx *cmdutil.Ctx, name string, zfile *zip.File) error {
+	path := filepath.Join(ctx.Flags.Directory, name)
 	if _, err := os.Stat(path); err == nil {
 		ctx.Log.Printf("\t%s %s.\n", colors.Blue("Exists"), path)
 		return nil
 	}
 
-	file, err := os.Create(path)
+	contents, err := zfile.Open()
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-	defer file.Sync()
+	defer contents.Close()
 
-	contents, err := zfile.Open()
+	data, err := ioutil.ReadAll(contents)
 	if err != nil {
 		return err
 	}
-	defer contents.Close()
 
-	_, err = io.Copy(file, contents)
-	if err == nil {
-		ctx.Log.Printf("\t%s %s.\n", colors.Green("Created"), file.Name())
+	if data, err = Expand(ctx, name, data); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return err
 	}
+	ctx.Log.Printf("\t%s %s.\n", colors.Green("Created"), path)
 	return nil
 }
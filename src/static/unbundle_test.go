@@ -1,14 +1,18 @@
 package static
 
 import (
+	"archive/zip"
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/Shopify/themekit/src/cmdutil"
+	"github.com/Shopify/themekit/src/env"
+	"github.com/Shopify/themekit/src/shopify"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -58,3 +62,85 @@ func TestUnbundle(t *testing.T) {
 		files[filepath.Join(testdirpath, "assets")],
 	)
 }
+
+func TestUnbundleStarter(t *testing.T) {
+	testdirpath := filepath.Join("_testdata", "starter")
+	defer os.RemoveAll(testdirpath)
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	f, _ := writer.Create("layout/theme.liquid")
+	f.Write([]byte("<title>{{% .Name %}} for {{% .Store %}} ({{% .ThemeID %}})</title>{{ content_for_layout }}"))
+	f, _ = writer.Create("config/settings_data.json")
+	f.Write([]byte("{}"))
+	writer.Close()
+
+	stdOut := bytes.NewBufferString("")
+	ctx := &cmdutil.Ctx{
+		Shop:  shopify.Shop{Name: "My Store"},
+		Env:   &env.Env{ThemeID: "42"},
+		Flags: cmdutil.Flags{Directory: testdirpath, Name: "Agency Base"},
+		Log:   log.New(stdOut, "", 0),
+	}
+
+	RegisterStarter("agency", buf.String())
+	defer delete(starters, "agency")
+	assert.True(t, HasStarter("agency"))
+	assert.Contains(t, Starters(), "agency")
+
+	assert.Nil(t, UnbundleStarter(ctx, "agency"))
+	data, err := ioutil.ReadFile(filepath.Join(testdirpath, "layout", "theme.liquid"))
+	assert.Nil(t, err)
+	assert.Equal(t, "<title>Agency Base for My Store (42)</title>{{ content_for_layout }}", string(data))
+
+	// existing files are not overwritten
+	ioutil.WriteFile(filepath.Join(testdirpath, "config", "settings_data.json"), []byte("changed"), 0644)
+	assert.Nil(t, UnbundleStarter(ctx, "agency"))
+	data, _ = ioutil.ReadFile(filepath.Join(testdirpath, "config", "settings_data.json"))
+	assert.Equal(t, "changed", string(data))
+	assert.Contains(t, stdOut.String(), "Exists")
+
+	err = UnbundleStarter(ctx, "nope")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "unknown starter nope, available starters are")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	ctx := &cmdutil.Ctx{
+		Env:   &env.Env{Domain: "store.myshopify.com", ThemeID: "42"},
+		Flags: cmdutil.Flags{Name: "Base"},
+	}
+
+	data, err := Expand(ctx, "layout/theme.liquid", []byte("{{% .Name %}} {{% .Domain %}} {{% .ThemeID %}} {{ shop.name }} {% if true %}{% endif %}"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Base store.myshopify.com 42 {{ shop.name }} {% if true %}{% endif %}", string(data))
+
+	binary := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a, '{', '{', '%'}
+	data, err = Expand(ctx, "assets/logo.png", binary)
+	assert.Nil(t, err)
+	assert.Equal(t, binary, data)
+
+	_, err = Expand(ctx, "layout/theme.liquid", []byte("{{% .Nope %}}"))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "could not expand layout/theme.liquid")
+	}
+
+	_, err = Expand(ctx, "layout/theme.liquid", []byte("{{% if %}}"))
+	assert.NotNil(t, err)
+}
+
+func TestWriteFileErrors(t *testing.T) {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	f, _ := writer.Create("layout/theme.liquid")
+	f.Write([]byte("content"))
+	writer.Close()
+
+	ctx := &cmdutil.Ctx{
+		Flags: cmdutil.Flags{Directory: filepath.Join("_testdata", "nope")},
+		Log:   log.New(bytes.NewBufferString(""), "", 0),
+	}
+	files, _ := getZipContents(buf.String())
+	assert.NotNil(t, writeFile(ctx, "layout/theme.liquid", files["layout"]["layout/theme.liquid"]))
+}